@@ -0,0 +1,57 @@
+package logrus
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Import path of this package, e.g. "github.com/logrus".
+var logrusPackage = reflect.TypeOf(Entry{}).PkgPath()
+
+// getCallerFrame returns the first frame of the call stack outside of this
+// package and of its hooks and formatters, i.e. the code which called Info,
+// Errorf, ... It is meant to be called from formatters and hooks while the
+// entry is being logged.
+func getCallerFrame() (runtime.Frame, bool) {
+	pcs := make([]uintptr, 32)
+	// 2 to skip runtime.Callers and getCallerFrame.
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !isLogrusFrame(frame) {
+			return frame, frame.PC != 0
+		}
+		if !more {
+			return runtime.Frame{}, false
+		}
+	}
+}
+
+func isLogrusFrame(frame runtime.Frame) bool {
+	if strings.HasSuffix(frame.File, "_test.go") {
+		return false
+	}
+	// Strip the receiver and function name, keeping the import path.
+	name := frame.Function
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		if j := strings.Index(name[i:], "."); j >= 0 {
+			name = name[:i+j]
+		}
+	} else if j := strings.Index(name, "."); j >= 0 {
+		name = name[:j]
+	}
+	// The hooks and formatters of this repository log on behalf of the
+	// caller too.
+	return name == logrusPackage ||
+		strings.HasPrefix(name, logrusPackage+"/hooks/") ||
+		strings.HasPrefix(name, logrusPackage+"/formatters/")
+}
+
+// CallerFrame returns the frame of the code which logged the entry being
+// formatted, skipping this package, its hooks and its formatters, for the
+// formatters of other packages.
+func CallerFrame() (runtime.Frame, bool) {
+	return getCallerFrame()
+}
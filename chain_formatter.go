@@ -0,0 +1,127 @@
+package logrus
+
+import (
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// FormatStage transforms an entry before the inner formatter of a
+// ChainFormatter formats it. It gets a snapshot of the entry, fields
+// included, which it may change freely. An error aborts the formatting.
+type FormatStage func(entry *Entry) error
+
+type chainFormatter struct {
+	inner  Formatter
+	stages []FormatStage
+}
+
+// ChainFormatter returns a Formatter running the stages, in order, on a
+// snapshot of every entry before formatting it with inner:
+//
+//	log.Formatter = logrus.ChainFormatter(&logrus.JSONFormatter{},
+//		logrus.StaticFields(logrus.Fields{"service": "member"}),
+//		logrus.RenameFields(map[string]string{"err": "error"}),
+//		logrus.UTC(),
+//	)
+//
+// The entry itself is never modified, so the same entry can safely be
+// formatted again or passed to hooks afterwards.
+func ChainFormatter(inner Formatter, stages ...FormatStage) Formatter {
+	return &chainFormatter{inner: inner, stages: stages}
+}
+
+func (f *chainFormatter) Format(entry *Entry) ([]byte, error) {
+	snapshot := *entry
+	snapshot.Data = copyFields(entry.Data)
+	for _, stage := range f.stages {
+		if err := stage(&snapshot); err != nil {
+			return nil, err
+		}
+	}
+	return f.inner.Format(&snapshot)
+}
+
+// StaticFields adds fields to every entry, unless the entry already has a
+// field of the same key.
+func StaticFields(fields Fields) FormatStage {
+	return func(entry *Entry) error {
+		for k, v := range fields {
+			if _, ok := entry.Data[k]; !ok {
+				entry.Data[k] = v
+			}
+		}
+		return nil
+	}
+}
+
+// RenameFields renames the fields of the keys of renames to their value.
+func RenameFields(renames map[string]string) FormatStage {
+	return func(entry *Entry) error {
+		for from, to := range renames {
+			if v, ok := entry.Data[from]; ok {
+				delete(entry.Data, from)
+				entry.Data[to] = v
+			}
+		}
+		return nil
+	}
+}
+
+// KeepFields drops all fields but the ones with the given keys.
+func KeepFields(keys ...string) FormatStage {
+	keep := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		keep[k] = true
+	}
+	return func(entry *Entry) error {
+		for k := range entry.Data {
+			if !keep[k] {
+				delete(entry.Data, k)
+			}
+		}
+		return nil
+	}
+}
+
+// DropFields drops the fields with the given keys.
+func DropFields(keys ...string) FormatStage {
+	return func(entry *Entry) error {
+		for _, k := range keys {
+			delete(entry.Data, k)
+		}
+		return nil
+	}
+}
+
+// InLocation converts the time of the entries to loc.
+func InLocation(loc *time.Location) FormatStage {
+	return func(entry *Entry) error {
+		entry.Time = entry.Time.In(loc)
+		return nil
+	}
+}
+
+// UTC converts the time of the entries to UTC.
+func UTC() FormatStage {
+	return InLocation(time.UTC)
+}
+
+// Redact redacts the entries with r, for formatters which need it while the
+// rest of the logger doesn't, see Logger.Redactor otherwise.
+func Redact(r *Redactor) FormatStage {
+	return func(entry *Entry) error {
+		*entry = *r.RedactEntry(entry)
+		return nil
+	}
+}
+
+// CallerField adds the "file.go:line" of the logging call as the field key.
+func CallerField(key string) FormatStage {
+	return func(entry *Entry) error {
+		if frame, ok := getCallerFrame(); ok {
+			entry.Data[key] = filepath.Base(frame.File) + ":" + strconv.Itoa(frame.Line)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,68 @@
+package logrus
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainFormatter(t *testing.T) {
+	f := ChainFormatter(&JSONFormatter{TimestampFormat: time.RFC3339},
+		StaticFields(Fields{"service": "member", "user": "default"}),
+		RenameFields(map[string]string{"err": "error"}),
+		DropFields("debug"),
+		UTC(),
+		Redact(&Redactor{Keys: []string{"password"}}),
+	)
+
+	entry := WithFields(Fields{"user": "walrus", "err": "boom", "debug": true, "password": "hunter2"})
+	entry.Time = time.Date(2017, 7, 5, 18, 56, 30, 0, time.FixedZone("CST", 8*3600))
+	entry.Message = "login"
+
+	b, err := f.Format(entry)
+	assert.NoError(t, err)
+
+	fields := Fields{}
+	assert.NoError(t, json.Unmarshal(b, &fields))
+	assert.Equal(t, Fields{
+		"time":     "2017-07-05T10:56:30Z",
+		"level":    "PANIC",
+		"msg":      "login",
+		"service":  "member",
+		"user":     "walrus",
+		"error":    "boom",
+		"password": "***",
+	}, fields)
+
+	// the entry is left alone
+	assert.Equal(t, Fields{"user": "walrus", "err": "boom", "debug": true, "password": "hunter2"}, entry.Data)
+	assert.Equal(t, "CST", entry.Time.Location().String())
+}
+
+func TestChainFormatterKeepFields(t *testing.T) {
+	f := ChainFormatter(&TextFormatter{DisableTimestamp: true, DisableColors: true}, KeepFields("a", "c"))
+
+	b, err := f.Format(WithFields(Fields{"a": 1, "b": 2, "c": 3}))
+	assert.NoError(t, err)
+	assert.Equal(t, "level=PANIC msg= a=1 c=3 \n", string(b))
+}
+
+func TestChainFormatterCaller(t *testing.T) {
+	f := ChainFormatter(&JSONFormatter{}, CallerField("caller"))
+
+	b, err := f.Format(WithField("a", 1))
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"caller":"chain_formatter_test.go:56"`)
+}
+
+func TestChainFormatterError(t *testing.T) {
+	f := ChainFormatter(&JSONFormatter{}, func(entry *Entry) error {
+		return errors.New("no")
+	})
+
+	_, err := f.Format(WithField("a", 1))
+	assert.EqualError(t, err, "no")
+}
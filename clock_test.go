@@ -0,0 +1,83 @@
+package logrus
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var clockTime = time.Date(2017, 7, 5, 18, 56, 30, 123000000, time.FixedZone("CST", 8*3600))
+
+func TestLoggerClock(t *testing.T) {
+	var buffer bytes.Buffer
+	hook := &redactRecorder{}
+
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &JSONFormatter{}
+	logger.Hooks.Add(hook)
+	logger.Clock = func() time.Time { return clockTime }
+
+	logger.Info("hello")
+	assert.Equal(t, `{"level":"INFO","msg":"hello","time":"2017-07-05T18:56:30+08:00"}`+"\n", buffer.String())
+	assert.Equal(t, clockTime, hook.entries[0].Time)
+}
+
+func TestSetClock(t *testing.T) {
+	var buffer bytes.Buffer
+	out, formatter := std.Out, std.Formatter
+	defer func() {
+		SetOutput(out)
+		SetFormatter(formatter)
+		SetClock(nil)
+	}()
+	SetOutput(&buffer)
+	SetFormatter(&JSONFormatter{})
+	SetClock(func() time.Time { return clockTime })
+
+	Info("hello")
+	assert.Contains(t, buffer.String(), `"time":"2017-07-05T18:56:30+08:00"`)
+}
+
+func TestFormattersLocation(t *testing.T) {
+	entry := NewEntry(New())
+	entry.Time = clockTime
+	entry.Level = InfoLevel
+	entry.Message = "hello"
+
+	formatters := []struct {
+		formatter Formatter
+		expected  string
+	}{
+		{&JSONFormatter{Location: time.UTC}, `"time":"2017-07-05T10:56:30Z"`},
+		{&TextFormatter{DisableColors: true, Location: time.UTC}, `time="2017-07-05T10:56:30Z"`},
+		{&ConsoleFormatter{DisableColors: true, DisableCaller: true, Location: time.UTC}, "INFO  10:56:30.123 hello"},
+		{mustTemplateFormatter(t, `{{timefmt "15:04" .Time}}`, time.UTC), "10:56"},
+		{&JSONFormatter{}, `"time":"2017-07-05T18:56:30+08:00"`},
+	}
+	for _, f := range formatters {
+		b, err := f.formatter.Format(entry)
+		assert.NoError(t, err)
+		assert.Contains(t, string(b), f.expected)
+	}
+}
+
+func mustTemplateFormatter(t *testing.T, text string, loc *time.Location) *TemplateFormatter {
+	f, err := NewTemplateFormatter(text, nil)
+	assert.NoError(t, err)
+	f.Location = loc
+	return f
+}
+
+func TestPrintFormatUsesEntryTime(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &LogFormatter{PrintFormat: "%D %T [%L] %M", Location: time.UTC}
+	logger.Clock = func() time.Time { return clockTime }
+
+	logger.Warn("hello")
+	assert.Equal(t, "2017-07-05 10:56:30.123 [WARN] hello\n", buffer.String())
+}
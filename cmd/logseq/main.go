@@ -0,0 +1,71 @@
+// Command logseq checks the sequence numbers of logs written with a
+// logrus.Sequencer, in JSON or text format, and reports the lines lost,
+// duplicated or reordered on their way:
+//
+//	$ logseq /var/log/myapp/app.log
+//	reorder: start_id 9c1e0f3a7b2d4e65 seq 1042 (line 1043)
+//	gap: start_id 9c1e0f3a7b2d4e65 seq 2077-2080
+//
+// Standard input is read when no file is given. Every file is checked on its
+// own. The exit status is 1 when an issue was found.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/logrus"
+)
+
+func main() {
+	seqField := flag.String("seq-field", "seq", "field holding the sequence number")
+	startIDField := flag.String("start-id-field", "start_id", "field holding the start id")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [file...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	checker := &logrus.SequenceChecker{SeqField: *seqField, StartIDField: *startIDField}
+
+	ok := true
+	if flag.NArg() == 0 {
+		ok = check(checker, "", os.Stdin)
+	}
+	for _, name := range flag.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "logseq: %v\n", err)
+			os.Exit(2)
+		}
+		if !check(checker, name, f) {
+			ok = false
+		}
+		f.Close()
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+func check(checker *logrus.SequenceChecker, name string, r io.Reader) bool {
+	prefix := ""
+	if name != "" {
+		prefix = name + ": "
+	}
+
+	report, err := checker.Check(r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logseq: %s%v\n", prefix, err)
+		os.Exit(2)
+	}
+	for _, issue := range report.Issues {
+		fmt.Printf("%s%s\n", prefix, issue)
+	}
+	if report.Entries == 0 {
+		fmt.Fprintf(os.Stderr, "logseq: %sno sequence numbers in %d lines\n", prefix, report.Lines)
+	}
+	return report.OK()
+}
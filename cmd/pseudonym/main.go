@@ -0,0 +1,89 @@
+// Command pseudonym re-derives the pseudonym a logrus.Pseudonymizer logs for
+// a known value, to find the lines of a user during an investigation:
+//
+//	$ pseudonym -key-file /etc/myapp/pseudonym-2017-07.key walrus@example.com
+//	3f1c2a9be07d45a1c6e2
+//
+// The key is read as hex, from -key or from the first line of -key-file. Use
+// the key whose id is logged in the "pseudonym_key" field of the entries.
+// With -ip, IP addresses are truncated instead, as done for IPFields.
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/logrus"
+)
+
+func main() {
+	keyHex := flag.String("key", "", "pseudonym key, hex encoded")
+	keyFile := flag.String("key-file", "", "file holding the hex encoded key")
+	ip := flag.Bool("ip", false, "truncate IP addresses instead")
+	ipv4Bits := flag.Int("ipv4-prefix", 24, "bits of IPv4 addresses kept with -ip")
+	ipv6Bits := flag.Int("ipv6-prefix", 48, "bits of IPv6 addresses kept with -ip")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s -key <hex> | -key-file <file> value...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *ip {
+		for _, value := range flag.Args() {
+			prefix, ok := logrus.TruncateIP(value, *ipv4Bits, *ipv6Bits)
+			if !ok {
+				fail("%q is not an IP address", value)
+			}
+			fmt.Println(prefix)
+		}
+		return
+	}
+
+	key, err := readKey(*keyHex, *keyFile)
+	if err != nil {
+		fail("%v", err)
+	}
+	for _, value := range flag.Args() {
+		fmt.Println(logrus.Pseudonym(key, value))
+	}
+}
+
+func readKey(keyHex, keyFile string) ([]byte, error) {
+	if keyFile != "" {
+		f, err := os.Open(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		line, err := bufio.NewReader(f).ReadString('\n')
+		if err != nil && line == "" {
+			return nil, fmt.Errorf("reading %s: %v", keyFile, err)
+		}
+		keyHex = line
+	}
+	if keyHex == "" {
+		return nil, fmt.Errorf("no key, use -key or -key-file")
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(keyHex))
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %v", err)
+	}
+	if len(key) < logrus.MinPseudonymKeySize {
+		return nil, fmt.Errorf("key must be at least %d bytes", logrus.MinPseudonymKeySize)
+	}
+	return key, nil
+}
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "pseudonym: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,327 @@
+package logrus
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// ColorMode is the kind of colors an output supports.
+type ColorMode int
+
+const (
+	// ColorsOff disables colors.
+	ColorsOff ColorMode = iota
+	// Colors16 uses the 8 standard colors and their bright variants.
+	Colors16
+	// Colors256 uses the xterm 256 color palette.
+	Colors256
+	// ColorsTrue uses 24-bit colors.
+	ColorsTrue
+)
+
+// Color is a foreground color of the terminal. The zero value leaves the
+// text uncolored. Colors are downgraded to what the output supports, so a
+// theme can use 24-bit colors and still work in a 16 color terminal.
+type Color struct {
+	kind    uint8 // 0: none, 1: ANSI code, 2: 256 palette, 3: RGB
+	n       uint8
+	r, g, b uint8
+}
+
+const (
+	colorNone uint8 = iota
+	colorANSI
+	color256
+	colorRGB
+)
+
+// ANSI returns one of the 16 standard colors by SGR code: 30-37 or 90-97.
+func ANSI(code int) Color {
+	return Color{kind: colorANSI, n: uint8(code)}
+}
+
+// Color256 returns a color of the xterm 256 color palette.
+func Color256(n uint8) Color {
+	return Color{kind: color256, n: n}
+}
+
+// RGB returns a 24-bit color.
+func RGB(r, g, b uint8) Color {
+	return Color{kind: colorRGB, r: r, g: g, b: b}
+}
+
+// The palette shared by all the formatters supporting colors.
+var (
+	Black   = ANSI(30)
+	Red     = ANSI(31)
+	Green   = ANSI(32)
+	Yellow  = ANSI(33)
+	Blue    = ANSI(34)
+	Magenta = ANSI(35)
+	Cyan    = ANSI(36)
+	Gray    = ANSI(37)
+	White   = ANSI(97)
+)
+
+var colorNames = map[string]Color{
+	"black":   Black,
+	"red":     Red,
+	"green":   Green,
+	"yellow":  Yellow,
+	"blue":    Blue,
+	"magenta": Magenta,
+	"cyan":    Cyan,
+	"gray":    Gray,
+	"white":   White,
+}
+
+// ParseColor parses a color name ("red", "cyan", ...), a 256 palette index
+// ("208") or a 24-bit color ("#ff8700").
+func ParseColor(s string) (Color, error) {
+	if c, ok := colorNames[strings.ToLower(s)]; ok {
+		return c, nil
+	}
+	if strings.HasPrefix(s, "#") && len(s) == 7 {
+		v, err := strconv.ParseUint(s[1:], 16, 32)
+		if err == nil {
+			return RGB(uint8(v>>16), uint8(v>>8), uint8(v)), nil
+		}
+	}
+	if n, err := strconv.ParseUint(s, 10, 8); err == nil {
+		return Color256(uint8(n)), nil
+	}
+	return Color{}, fmt.Errorf("logrus: invalid color %q", s)
+}
+
+// sgr returns the SGR parameters selecting c in the given mode.
+func (c Color) sgr(mode ColorMode) string {
+	switch c.kind {
+	case colorRGB:
+		switch mode {
+		case ColorsTrue:
+			return fmt.Sprintf("38;2;%d;%d;%d", c.r, c.g, c.b)
+		case Colors256:
+			return "38;5;" + strconv.Itoa(int(rgbTo256(c.r, c.g, c.b)))
+		}
+		return strconv.Itoa(rgbToANSI(c.r, c.g, c.b))
+	case color256:
+		if mode >= Colors256 {
+			return "38;5;" + strconv.Itoa(int(c.n))
+		}
+		if c.n < 8 {
+			return strconv.Itoa(30 + int(c.n))
+		}
+		if c.n < 16 {
+			return strconv.Itoa(90 + int(c.n) - 8)
+		}
+		r, g, b := palette256ToRGB(c.n)
+		return strconv.Itoa(rgbToANSI(r, g, b))
+	}
+	return strconv.Itoa(int(c.n))
+}
+
+// Sprint colors s for an output of the given mode.
+func (c Color) Sprint(mode ColorMode, s string) string {
+	if c.kind == colorNone || mode == ColorsOff {
+		return s
+	}
+	return "\x1b[" + c.sgr(mode) + "m" + s + "\x1b[0m"
+}
+
+func rgbTo256(r, g, b uint8) uint8 {
+	if r == g && g == b {
+		// grayscale ramp 232-255
+		if r < 8 {
+			return 16
+		}
+		if r > 248 {
+			return 231
+		}
+		return 232 + uint8((int(r)-8)*24/247)
+	}
+	// nearest of the cube levels 0, 95, 135, 175, 215, 255
+	q := func(v uint8) int {
+		switch {
+		case v < 48:
+			return 0
+		case v < 115:
+			return 1
+		}
+		return (int(v) - 35) / 40
+	}
+	return uint8(16 + 36*q(r) + 6*q(g) + q(b))
+}
+
+func palette256ToRGB(n uint8) (r, g, b uint8) {
+	if n >= 232 {
+		v := 8 + (n-232)*10
+		return v, v, v
+	}
+	n -= 16
+	level := func(v uint8) uint8 {
+		if v == 0 {
+			return 0
+		}
+		return 55 + v*40
+	}
+	return level(n / 36), level((n / 6) % 6), level(n % 6)
+}
+
+func rgbToANSI(r, g, b uint8) int {
+	code := 0
+	if r > 127 {
+		code |= 1
+	}
+	if g > 127 {
+		code |= 2
+	}
+	if b > 127 {
+		code |= 4
+	}
+	max := r
+	if g > max {
+		max = g
+	}
+	if b > max {
+		max = b
+	}
+	if max > 200 && code != 0 {
+		return 90 + code // bright
+	}
+	return 30 + code
+}
+
+// ColorTheme assigns colors to the parts of an entry.
+type ColorTheme struct {
+	// Levels colors the level and, unless overridden in Keys, the field keys.
+	Levels map[Level]Color
+	// Keys colors the keys of specific fields.
+	Keys map[string]Color
+	// Timestamp and Caller color these columns when a formatter prints them.
+	Timestamp Color
+	Caller    Color
+	// Error colors error fields.
+	Error Color
+}
+
+// DefaultColorTheme is used by formatters without a Theme.
+var DefaultColorTheme = &ColorTheme{
+	Levels: map[Level]Color{
+		DebugLevel: Gray,
+		InfoLevel:  Cyan,
+		WarnLevel:  Yellow,
+		ErrorLevel: Red,
+		FatalLevel: Red,
+		PanicLevel: Red,
+	},
+	Timestamp: Gray,
+	Caller:    Gray,
+	Error:     Red,
+}
+
+func themeOrDefault(theme *ColorTheme) *ColorTheme {
+	if theme == nil {
+		return DefaultColorTheme
+	}
+	return theme
+}
+
+// Level returns the color of level.
+func (t *ColorTheme) Level(level Level) Color {
+	return t.Levels[level]
+}
+
+// Key returns the color of a field key in an entry of the given level.
+func (t *ColorTheme) Key(key string, level Level) Color {
+	if c, ok := t.Keys[key]; ok {
+		return c
+	}
+	return t.Levels[level]
+}
+
+// ColorModeFor decides how to color output written to out. In order:
+//
+//   - disable turns colors off and force turns them on,
+//   - FORCE_COLOR or CLICOLOR_FORCE set to a non zero value turn them on,
+//     FORCE_COLOR=0 turns them off,
+//   - NO_COLOR set to a non-empty value, CLICOLOR=0 or TERM=dumb turn them
+//     off,
+//   - otherwise colors are used when out is a terminal (not on Windows).
+//
+// The kind of colors is taken from FORCE_COLOR (1, 2 or 3), COLORTERM
+// (truecolor or 24bit) and TERM (*256color*).
+func ColorModeFor(out io.Writer, force, disable bool) ColorMode {
+	if disable {
+		return ColorsOff
+	}
+
+	forceEnv := os.Getenv("FORCE_COLOR")
+	switch strings.ToLower(forceEnv) {
+	case "0", "false", "no":
+		if !force {
+			return ColorsOff
+		}
+		forceEnv = ""
+	case "1":
+		return Colors16
+	case "2":
+		return Colors256
+	case "3":
+		return ColorsTrue
+	}
+	if force || forceEnv != "" || (os.Getenv("CLICOLOR_FORCE") != "" && os.Getenv("CLICOLOR_FORCE") != "0") {
+		return terminalColorMode()
+	}
+
+	if os.Getenv("NO_COLOR") != "" {
+		return ColorsOff
+	}
+	if os.Getenv("CLICOLOR") == "0" || os.Getenv("TERM") == "dumb" {
+		return ColorsOff
+	}
+	if runtime.GOOS == "windows" || !isTerminalWriter(out) {
+		return ColorsOff
+	}
+	return terminalColorMode()
+}
+
+func terminalColorMode() ColorMode {
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return ColorsTrue
+	}
+	if strings.Contains(os.Getenv("TERM"), "256color") {
+		return Colors256
+	}
+	return Colors16
+}
+
+// entryColorMode decides how to color an entry written to its output.
+func entryColorMode(entry *Entry, force, disable bool) ColorMode {
+	return ColorModeFor(entry.output(), force, disable)
+}
+
+// FormatFor formats entry with f for out rather than for the Out of its
+// logger, the colors being chosen for out: for hooks writing the entries to
+// their own files or terminals.
+func FormatFor(f Formatter, entry *Entry, out io.Writer) ([]byte, error) {
+	e := *entry
+	e.out = out
+	if out == nil {
+		e.out = ioutil.Discard
+	}
+	return f.Format(&e)
+}
+
+// isTerminalWriter tells whether w is a file attached to a terminal.
+func isTerminalWriter(w io.Writer) bool {
+	f, ok := w.(interface {
+		Fd() uintptr
+	})
+	return ok && isTerminalFd(f.Fd())
+}
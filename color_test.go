@@ -0,0 +1,190 @@
+package logrus
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func withEnv(t *testing.T, env map[string]string, f func()) {
+	keys := []string{"FORCE_COLOR", "CLICOLOR_FORCE", "CLICOLOR", "NO_COLOR", "COLORTERM", "TERM"}
+	saved := make(map[string]*string, len(keys))
+	for _, k := range keys {
+		if v, ok := os.LookupEnv(k); ok {
+			saved[k] = &v
+		}
+		os.Unsetenv(k)
+	}
+	defer func() {
+		for _, k := range keys {
+			if v := saved[k]; v != nil {
+				os.Setenv(k, *v)
+			} else {
+				os.Unsetenv(k)
+			}
+		}
+	}()
+	for k, v := range env {
+		os.Setenv(k, v)
+	}
+	f()
+}
+
+func TestColorModeFor(t *testing.T) {
+	out := &bytes.Buffer{}
+	cases := []struct {
+		env            map[string]string
+		force, disable bool
+		expected       ColorMode
+	}{
+		{nil, false, false, ColorsOff},
+		{nil, true, false, Colors16},
+		{nil, true, true, ColorsOff},
+		{map[string]string{"COLORTERM": "truecolor"}, true, false, ColorsTrue},
+		{map[string]string{"TERM": "xterm-256color"}, true, false, Colors256},
+		{map[string]string{"FORCE_COLOR": "1"}, false, false, Colors16},
+		{map[string]string{"FORCE_COLOR": "3"}, false, false, ColorsTrue},
+		{map[string]string{"FORCE_COLOR": "0"}, false, false, ColorsOff},
+		{map[string]string{"FORCE_COLOR": "0"}, true, false, Colors16},
+		{map[string]string{"FORCE_COLOR": "1"}, false, true, ColorsOff},
+		{map[string]string{"CLICOLOR_FORCE": "1"}, false, false, Colors16},
+		{map[string]string{"NO_COLOR": ""}, false, false, ColorsOff},
+		{map[string]string{"NO_COLOR": "1"}, true, false, Colors16},
+	}
+	for _, c := range cases {
+		withEnv(t, c.env, func() {
+			assert.Equal(t, c.expected, ColorModeFor(out, c.force, c.disable), "%v force=%v disable=%v", c.env, c.force, c.disable)
+		})
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	c, err := ParseColor("Red")
+	assert.NoError(t, err)
+	assert.Equal(t, Red, c)
+
+	c, err = ParseColor("208")
+	assert.NoError(t, err)
+	assert.Equal(t, Color256(208), c)
+
+	c, err = ParseColor("#ff8700")
+	assert.NoError(t, err)
+	assert.Equal(t, RGB(0xff, 0x87, 0x00), c)
+
+	for _, s := range []string{"purple", "256", "#ff87", "#gggggg"} {
+		_, err = ParseColor(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestColorDowngrade(t *testing.T) {
+	orange := RGB(0xff, 0x87, 0x00)
+	assert.Equal(t, "\x1b[38;2;255;135;0mx\x1b[0m", orange.Sprint(ColorsTrue, "x"))
+	assert.Equal(t, "\x1b[38;5;208mx\x1b[0m", orange.Sprint(Colors256, "x"))
+	assert.Equal(t, "\x1b[93mx\x1b[0m", orange.Sprint(Colors16, "x"))
+	assert.Equal(t, "x", orange.Sprint(ColorsOff, "x"))
+
+	assert.Equal(t, "\x1b[38;5;9mx\x1b[0m", Color256(9).Sprint(ColorsTrue, "x"))
+	assert.Equal(t, "\x1b[91mx\x1b[0m", Color256(9).Sprint(Colors16, "x"))
+	assert.Equal(t, "\x1b[31mx\x1b[0m", Red.Sprint(ColorsTrue, "x"))
+	assert.Equal(t, "x", Color{}.Sprint(ColorsTrue, "x"))
+}
+
+func TestTextFormatterTheme(t *testing.T) {
+	withEnv(t, nil, func() {
+		f := &TextFormatter{
+			ForceColors:   true,
+			FullTimestamp: true,
+			Theme: &ColorTheme{
+				Levels: map[Level]Color{InfoLevel: Green},
+				Keys:   map[string]Color{"user": Magenta},
+			},
+		}
+
+		entry := WithFields(Fields{"user": "walrus", "size": 10})
+		entry.Level = InfoLevel
+		entry.Message = "hi"
+
+		b, err := f.Format(entry)
+		assert.NoError(t, err)
+		assert.Contains(t, string(b), "\x1b[32mINFO\x1b[0m[")
+		assert.Contains(t, string(b), " \x1b[32msize\x1b[0m=10")
+		assert.Contains(t, string(b), " \x1b[35muser\x1b[0m=walrus")
+	})
+}
+
+func TestFormattersDetectOutputTerminal(t *testing.T) {
+	withEnv(t, nil, func() {
+		logger := New()
+		logger.Out = &bytes.Buffer{}
+
+		entry := NewEntry(logger)
+		entry.Level = WarnLevel
+		entry.Message = "hi"
+
+		for _, f := range []Formatter{&TextFormatter{}, &ConsoleFormatter{DisableCaller: true}} {
+			b, err := f.Format(entry)
+			assert.NoError(t, err)
+			assert.NotContains(t, string(b), "\x1b[")
+		}
+	})
+}
+
+func TestColorModeForTerminal(t *testing.T) {
+	tty, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		t.Skip("no pseudo terminal:", err)
+	}
+	defer tty.Close()
+	if !isTerminalWriter(tty) {
+		t.Skip("/dev/ptmx is not a terminal")
+	}
+
+	cases := []struct {
+		env      map[string]string
+		expected ColorMode
+	}{
+		{nil, Colors16},
+		{map[string]string{"NO_COLOR": ""}, Colors16},
+		{map[string]string{"NO_COLOR": "1"}, ColorsOff},
+		{map[string]string{"TERM": "dumb"}, ColorsOff},
+	}
+	for _, c := range cases {
+		withEnv(t, c.env, func() {
+			assert.Equal(t, c.expected, ColorModeFor(tty, false, false), "%v", c.env)
+		})
+	}
+}
+
+func TestFormatFor(t *testing.T) {
+	tty, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		t.Skip("no pseudo terminal:", err)
+	}
+	defer tty.Close()
+	if !isTerminalWriter(tty) {
+		t.Skip("/dev/ptmx is not a terminal")
+	}
+
+	withEnv(t, nil, func() {
+		logger := New()
+		logger.Out = tty
+
+		entry := NewEntry(logger)
+		entry.Level = WarnLevel
+		entry.Message = "hi"
+
+		for _, f := range []Formatter{&TextFormatter{}, &ConsoleFormatter{DisableCaller: true}, &TemplateFormatter{}} {
+			b, err := f.Format(entry)
+			assert.NoError(t, err)
+			assert.Contains(t, string(b), "\x1b[", "%T", f)
+
+			// written by a hook to a file
+			b, err = FormatFor(f, entry, &bytes.Buffer{})
+			assert.NoError(t, err)
+			assert.NotContains(t, string(b), "\x1b[", "%T", f)
+		}
+	})
+}
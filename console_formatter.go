@@ -0,0 +1,297 @@
+package logrus
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Nested values deeper than this are printed on one line.
+const consoleMaxDepth = 8
+
+// ConsoleFormatter is meant for humans reading logs in a terminal during
+// development. Every entry starts with aligned level, time and caller
+// columns followed by the first line of the message:
+//
+//	INFO  15:04:05.000 main.go:31            A group of walrus emerges
+//	    animal: walrus
+//	    size: 10
+//
+// The remaining lines of the message and the fields are indented below it,
+// one field per line, with maps, structs and slices rendered as trees and
+// errors highlighted along with their stack trace, if any.
+type ConsoleFormatter struct {
+	// Set to true to bypass checking for a TTY before outputting colors.
+	ForceColors bool
+
+	// Force disabling colors.
+	DisableColors bool
+
+	// Theme of the colors, defaults to DefaultColorTheme.
+	Theme *ColorTheme
+
+	// Disable the time column.
+	DisableTimestamp bool
+
+	// TimestampFormat of the time column, defaults to "15:04:05.000".
+	TimestampFormat string
+
+	// Location of the time column.
+	Location *time.Location
+
+	// Disable the caller column.
+	DisableCaller bool
+
+	// CallerWidth is the width of the caller column, defaults to 24.
+	CallerWidth int
+
+	// Hyperlinks wraps the caller in an OSC 8 hyperlink to the source file,
+	// which supporting terminals let you click to open. Written along with
+	// the colors only.
+	Hyperlinks bool
+
+	// Indent of continuation lines and fields, defaults to four spaces.
+	Indent string
+
+	// Sanitize each line of the message and the fields, see SanitizePolicy.
+	Sanitize SanitizePolicy
+}
+
+func (f *ConsoleFormatter) Format(entry *Entry) ([]byte, error) {
+	keys := make([]string, 0, len(entry.Data))
+	for k := range entry.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	mode := entryColorMode(entry, f.ForceColors, f.DisableColors)
+	theme := themeOrDefault(f.Theme)
+	sanitize := f.Sanitize.Resolve(SanitizeEscape)
+
+	timestampFormat := f.TimestampFormat
+	if timestampFormat == "" {
+		timestampFormat = "15:04:05.000"
+	}
+	callerWidth := f.CallerWidth
+	if callerWidth == 0 {
+		callerWidth = 24
+	}
+	indent := f.Indent
+	if indent == "" {
+		indent = "    "
+	}
+
+	b := &bytes.Buffer{}
+	b.WriteString(theme.Level(entry.Level).Sprint(mode, fmt.Sprintf("%-5s", entry.Level.String())))
+	if !f.DisableTimestamp {
+		b.WriteByte(' ')
+		b.WriteString(theme.Timestamp.Sprint(mode, timeIn(entry.Time, f.Location).Format(timestampFormat)))
+	}
+	if !f.DisableCaller {
+		b.WriteByte(' ')
+		f.appendCaller(b, mode, theme, callerWidth)
+	}
+
+	lines := strings.Split(strings.TrimRight(entry.Message, "\n"), "\n")
+	b.WriteByte(' ')
+	b.WriteString(sanitize.SanitizeMessage(lines[0]))
+	b.WriteByte('\n')
+	for _, line := range lines[1:] {
+		b.WriteString(indent)
+		b.WriteString(sanitize.SanitizeMessage(line))
+		b.WriteByte('\n')
+	}
+
+	for _, k := range keys {
+		f.appendField(b, mode, theme, sanitize, entry.Level, indent, k, entry.Data[k])
+	}
+	return b.Bytes(), nil
+}
+
+// appendCaller writes "file.go:line" padded to width.
+func (f *ConsoleFormatter) appendCaller(b *bytes.Buffer, mode ColorMode, theme *ColorTheme, width int) {
+	frame, ok := getCallerFrame()
+	short := "???"
+	if ok {
+		short = filepath.Base(frame.File) + ":" + strconv.Itoa(frame.Line)
+	}
+	pad := ""
+	if n := width - len(short); n > 0 {
+		pad = strings.Repeat(" ", n)
+	}
+
+	if ok && f.Hyperlinks && mode != ColorsOff {
+		// OSC 8 ; params ; URI ST text OSC 8 ; ; ST
+		path := frame.File
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		fmt.Fprintf(b, "\x1b]8;;%s\x1b\\", fileURL(path, frame.Line))
+		b.WriteString(theme.Caller.Sprint(mode, short))
+		b.WriteString("\x1b]8;;\x1b\\")
+	} else {
+		b.WriteString(theme.Caller.Sprint(mode, short))
+	}
+	b.WriteString(pad)
+}
+
+// fileURL returns the file URL of line of the absolute path of a source file,
+// escaped, with the drive of Windows paths as first element, e.g.
+// "file:///C:/src/main.go#31".
+func fileURL(path string, line int) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	u := url.URL{Scheme: "file", Path: path, Fragment: strconv.Itoa(line)}
+	return u.String()
+}
+
+func (f *ConsoleFormatter) appendField(b *bytes.Buffer, mode ColorMode, theme *ColorTheme, sanitize SanitizePolicy, level Level, indent, key string, value interface{}) {
+	b.WriteString(indent)
+	key = sanitize.SanitizeField(key)
+	if err, ok := value.(error); ok {
+		b.WriteString(theme.Error.Sprint(mode, key))
+		b.WriteString(": ")
+		b.WriteString(theme.Error.Sprint(mode, sanitize.SanitizeField(err.Error())))
+		b.WriteByte('\n')
+		// Errors carrying a stack (e.g. github.com/pkg/errors) print it with %+v.
+		if trace := fmt.Sprintf("%+v", err); trace != err.Error() {
+			for _, line := range strings.Split(strings.TrimRight(trace, "\n"), "\n") {
+				b.WriteString(indent + indent)
+				b.WriteString(sanitize.SanitizeField(line))
+				b.WriteByte('\n')
+			}
+		}
+		return
+	}
+
+	b.WriteString(theme.Key(key, level).Sprint(mode, key))
+	b.WriteByte(':')
+	f.appendValue(b, sanitize, indent, indent, reflect.ValueOf(value), 0)
+}
+
+// appendValue writes v after a key: scalars on the same line, maps, structs
+// and slices as an indented tree on the following lines.
+func (f *ConsoleFormatter) appendValue(b *bytes.Buffer, sanitize SanitizePolicy, prefix, indent string, v reflect.Value, depth int) {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !v.IsNil() {
+		if isConsoleScalar(v) {
+			break
+		}
+		v = v.Elem()
+	}
+
+	if consoleLen(v) == 0 || isConsoleScalar(v) || depth >= consoleMaxDepth {
+		b.WriteByte(' ')
+		b.WriteString(consoleScalar(v, sanitize))
+		b.WriteByte('\n')
+		return
+	}
+	b.WriteByte('\n')
+
+	prefix += indent
+	switch v.Kind() {
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, k := range keys {
+			b.WriteString(prefix)
+			b.WriteString(sanitize.SanitizeField(fmt.Sprint(k.Interface())))
+			b.WriteByte(':')
+			f.appendValue(b, sanitize, prefix, indent, v.MapIndex(k), depth+1)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if t.Field(i).PkgPath != "" {
+				continue // unexported
+			}
+			b.WriteString(prefix)
+			b.WriteString(t.Field(i).Name)
+			b.WriteByte(':')
+			f.appendValue(b, sanitize, prefix, indent, v.Field(i), depth+1)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			b.WriteString(prefix)
+			b.WriteByte('-')
+			f.appendValue(b, sanitize, prefix, indent, v.Index(i), depth+1)
+		}
+	}
+}
+
+// isConsoleScalar tells whether v is printed on one line: basic kinds and
+// anything implementing error or fmt.Stringer.
+func isConsoleScalar(v reflect.Value) bool {
+	if v.CanInterface() {
+		switch v.Interface().(type) {
+		case error, fmt.Stringer, []byte:
+			return true
+		}
+	}
+	switch v.Kind() {
+	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array, reflect.Ptr, reflect.Interface:
+		return false
+	}
+	return true
+}
+
+// consoleLen is the number of children v has in the tree.
+func consoleLen(v reflect.Value) int {
+	if !v.IsValid() {
+		return 0
+	}
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return v.Len()
+	case reflect.Struct:
+		n := 0
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				n++
+			}
+		}
+		return n
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+	}
+	return 1
+}
+
+// consoleScalar formats v on one line. Strings are quoted with escapes when
+// needed, so they only need sanitizing when control characters are to be
+// replaced or stripped.
+func consoleScalar(v reflect.Value, sanitize SanitizePolicy) string {
+	if !v.IsValid() {
+		return "<nil>"
+	}
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return "<nil>"
+	}
+	if !v.CanInterface() {
+		return sanitize.SanitizeField(fmt.Sprint(v))
+	}
+	switch i := v.Interface().(type) {
+	case string:
+		if sanitize.Fields != SanitizeEscape {
+			i = sanitize.SanitizeField(i)
+		}
+		if needsQuoting(i) && i != "" {
+			return i
+		}
+		return strconv.Quote(i)
+	case error:
+		return sanitize.SanitizeField(i.Error())
+	}
+	return sanitize.SanitizeField(fmt.Sprintf("%v", v.Interface()))
+}
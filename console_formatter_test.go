@@ -0,0 +1,81 @@
+package logrus
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type consoleUser struct {
+	Name   string
+	Tags   []string
+	secret string
+}
+
+func TestConsoleFormatter(t *testing.T) {
+	f := &ConsoleFormatter{DisableColors: true, DisableCaller: true}
+
+	entry := WithFields(Fields{
+		"user":  consoleUser{Name: "walrus", Tags: []string{"a", "b"}, secret: "x"},
+		"meta":  map[string]interface{}{"size": 10, "empty": []int{}},
+		"error": errors.New("boom"),
+		"quote": "x y",
+	})
+	entry.Level = WarnLevel
+	entry.Time = time.Date(2017, 7, 5, 10, 56, 30, 0, time.UTC)
+	entry.Message = "first line\nsecond line"
+
+	b, err := f.Format(entry)
+	assert.NoError(t, err)
+
+	expected := strings.Join([]string{
+		"WARN  10:56:30.000 first line",
+		"    second line",
+		"    error: boom",
+		"    meta:",
+		"        empty: []",
+		"        size: 10",
+		`    quote: "x y"`,
+		"    user:",
+		"        Name: walrus",
+		"        Tags:",
+		"            - a",
+		"            - b",
+		"",
+	}, "\n")
+	assert.Equal(t, expected, string(b))
+}
+
+func TestConsoleFormatterCaller(t *testing.T) {
+	f := &ConsoleFormatter{DisableColors: true, DisableTimestamp: true, CallerWidth: 30, Hyperlinks: true}
+
+	entry := WithField("nil", nil)
+	entry.Level = InfoLevel
+
+	_, file, line, _ := runtime.Caller(0)
+	b, err := f.Format(entry)
+	assert.NoError(t, err)
+
+	// no hyperlink without colors, e.g. in files
+	caller := fmt.Sprintf("console_formatter_test.go:%d", line+1)
+	assert.Equal(t, "INFO  "+caller+strings.Repeat(" ", 30-len(caller))+" \n    nil: <nil>\n", string(b))
+
+	f.DisableColors, f.ForceColors = false, true
+	_, file, line, _ = runtime.Caller(0)
+	b, err = f.Format(entry)
+	assert.NoError(t, err)
+	link := "\x1b]8;;" + fileURL(file, line+1) + "\x1b\\"
+	assert.Contains(t, string(b), " "+link)
+	assert.Contains(t, string(b), "\x1b]8;;\x1b\\")
+}
+
+func TestFileURL(t *testing.T) {
+	assert.Equal(t, "file:///src/my%20app/main%231.go#31", fileURL("/src/my app/main#1.go", 31))
+	// a Windows path, the drive coming after the empty host
+	assert.Equal(t, "file:///C:/src/main.go#7", fileURL("C:/src/main.go", 7))
+}
@@ -26,6 +26,21 @@ type Entry struct {
 
 	// Message passed to Debug, Info, Warn, Error, Fatal or Panic
 	Message string
+
+	// out is the writer the entry is formatted for when not the Out of the
+	// logger, see FormatFor.
+	out io.Writer
+}
+
+// output returns the writer the entry is formatted for.
+func (entry *Entry) output() io.Writer {
+	if entry.out != nil {
+		return entry.out
+	}
+	if entry.Logger != nil {
+		return entry.Logger.Out
+	}
+	return nil
 }
 
 func NewEntry(logger *Logger) *Entry {
@@ -71,10 +86,25 @@ func (entry *Entry) WithFields(fields Fields) *Entry {
 }
 
 func (entry *Entry) log(level Level, msg string) {
-	entry.Time = time.Now()
+	entry.Time = entry.Logger.now()
 	entry.Level = level
 	entry.Message = msg
 
+	if entry.Logger.Sequencer != nil {
+		entry = entry.Logger.Sequencer.SequenceEntry(entry)
+	}
+
+	// Redact the entry before anything gets to see it.
+	if entry.Logger.Redactor != nil {
+		entry = entry.Logger.Redactor.RedactEntry(entry)
+	}
+	if entry.Logger.Pseudonymizer != nil {
+		entry = entry.Logger.Pseudonymizer.PseudonymizeEntry(entry)
+	}
+	if entry.Logger.SizeLimits != nil {
+		entry = entry.Logger.SizeLimits.LimitEntry(entry)
+	}
+
 	// 先让添加的Hooks记录日志;
 	if err := entry.Logger.Hooks.Fire(level, entry); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to fire hook: %v\n", err)
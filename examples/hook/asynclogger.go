@@ -17,7 +17,7 @@ type LogConfig struct {
     FileName    string  `json:"filename"`
     MaxLines    int     `json:"maxlines"`
     MaxSize     int     `json:"maxsize"`
-    Daliy       bool    `json:"daliy"`
+    Daily       bool    `json:"daily"`
     MaxDays     int     `json:"maxdays"`
     Rotate      bool    `json:"rotate"`
     Level       int     `json:"level"`
@@ -50,8 +50,14 @@ func main() {
     }
     // PrintFormat只会在GLog.Hooks.Add()时被修改;
     // 另外,同步模式的日志记录底层调用的是log模块,所以会重复打印 日期和时间;所以,对于选择非异步刷新模式记录日志时,可以不选 %d %T
-    GLog.Hooks.Add(file.NewHook(string(json), "[%d %T %s] [%L] %M"))
-    //GLog.Hooks.Add(file.NewHook(string(json), "%s [%L] %M")) // for 同步日志;
+    hook, err := file.NewHookJSON(string(json), "[%d %T %s] [%L] %M")
+    //hook, err := file.NewHookJSON(string(json), "%s [%L] %M") // for 同步日志;
+    if err != nil {
+        fmt.Printf("Err: %s\n", err)
+        return
+    }
+    defer hook.Close()
+    GLog.Hooks.Add(hook)
 
     golen := 1000
     var wg sync.WaitGroup
@@ -32,7 +32,11 @@ func init() {
     	"rotate"  : true,
     	"level"   : 5
      }`
-    log.Hooks.Add(file.NewHook(config_json, "[%s] [%L] %M"))
+    hook, err := file.NewHookJSON(config_json, "[%s] [%L] %M")
+    if err != nil {
+        log.Fatalf("file hook: %s", err)
+    }
+    log.Hooks.Add(hook)
 }
 
 func main() {
@@ -32,7 +32,11 @@ func init() {
     	"rotate"  : true,
     	"level"   : 5
      }`
-    GLog.Hooks.Add(file.NewHook(config_json, "[%s] [%L] %M"))
+    hook, err := file.NewHookJSON(config_json, "[%s] [%L] %M")
+    if err != nil {
+        GLog.Fatalf("file hook: %s", err)
+    }
+    GLog.Hooks.Add(hook)
     GLog.Out = nil
     GLog.Formatter = nil
 }
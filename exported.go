@@ -2,6 +2,7 @@ package logrus
 
 import (
 	"io"
+	"time"
 )
 
 var (
@@ -41,6 +42,41 @@ func GetLevel() Level {
 	return std.Level
 }
 
+// SetRedactor sets the standard logger redactor.
+func SetRedactor(redactor *Redactor) {
+	std.mu.Lock()
+	defer std.mu.Unlock()
+	std.Redactor = redactor
+}
+
+// SetPseudonymizer sets the standard logger pseudonymizer.
+func SetPseudonymizer(pseudonymizer *Pseudonymizer) {
+	std.mu.Lock()
+	defer std.mu.Unlock()
+	std.Pseudonymizer = pseudonymizer
+}
+
+// SetSizeLimits sets the standard logger size limits.
+func SetSizeLimits(limits *SizeLimits) {
+	std.mu.Lock()
+	defer std.mu.Unlock()
+	std.SizeLimits = limits
+}
+
+// SetSequencer sets the standard logger sequencer.
+func SetSequencer(sequencer *Sequencer) {
+	std.mu.Lock()
+	defer std.mu.Unlock()
+	std.Sequencer = sequencer
+}
+
+// SetClock sets the standard logger clock.
+func SetClock(clock func() time.Time) {
+	std.mu.Lock()
+	defer std.mu.Unlock()
+	std.Clock = clock
+}
+
 // AddHook adds a hook to the standard logger hooks.
 func AddHook(hook Hook) {
 	std.mu.Lock()
@@ -6,6 +6,14 @@ import (
 
 const DefaultTimestampFormat = time.RFC3339
 
+// timeIn returns t in loc, or t as is when loc is nil.
+func timeIn(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		return t
+	}
+	return t.In(loc)
+}
+
 // The Formatter interface is used to implement a custom Formatter. It takes an
 // `Entry`. It exposes all the fields, including the default ones:
 //
@@ -0,0 +1,186 @@
+package cbor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/logrus"
+)
+
+// Major types, RFC 8949 section 3.1.
+const (
+	majorUint   = 0
+	majorNegInt = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorTag    = 6
+	majorSimple = 7
+)
+
+// Tag of epoch based date/time, RFC 8949 section 3.4.2.
+const tagEpochTime = 1
+
+// CBORFormatter encodes entries as CBOR records, each prefixed with its
+// length as an unsigned varint. A record is the array
+//
+//	[time, level, message, fields]
+//
+// with the time as an epoch based date/time (tag 1, an integer or a float
+// with microsecond precision), the level as a small integer and the fields
+// as a map keeping the type of their values. Use a Decoder to read a stream
+// of records back.
+// Specification: https://www.rfc-editor.org/rfc/rfc8949
+type CBORFormatter struct {
+	// Sanitize, see logrus.SanitizePolicy, for records decoded into text.
+	Sanitize logrus.SanitizePolicy
+}
+
+// Record is a decoded entry.
+type Record struct {
+	Time    time.Time
+	Level   logrus.Level
+	Message string
+	Data    logrus.Fields
+}
+
+func (f *CBORFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	entry = logrus.SanitizeEntry(entry, f.Sanitize, logrus.SanitizeNone)
+
+	b := make([]byte, 0, 64+32*len(entry.Data))
+	b = appendHead(b, majorArray, 4)
+	b = appendTime(b, entry.Time)
+	b = appendHead(b, majorUint, uint64(entry.Level))
+	b = appendText(b, entry.Message)
+	b = appendHead(b, majorMap, uint64(len(entry.Data)))
+	for k, v := range entry.Data {
+		b = appendText(b, k)
+		b = appendValue(b, v)
+	}
+
+	var prefix [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(prefix[:], uint64(len(b)))
+	return append(prefix[:n:n], b...), nil
+}
+
+func appendValue(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, majorSimple<<5|22)
+	case bool:
+		if v {
+			return append(b, majorSimple<<5|21)
+		}
+		return append(b, majorSimple<<5|20)
+	case int:
+		return appendInt(b, int64(v))
+	case int8:
+		return appendInt(b, int64(v))
+	case int16:
+		return appendInt(b, int64(v))
+	case int32:
+		return appendInt(b, int64(v))
+	case int64:
+		return appendInt(b, v)
+	case uint:
+		return appendHead(b, majorUint, uint64(v))
+	case uint8:
+		return appendHead(b, majorUint, uint64(v))
+	case uint16:
+		return appendHead(b, majorUint, uint64(v))
+	case uint32:
+		return appendHead(b, majorUint, uint64(v))
+	case uint64:
+		return appendHead(b, majorUint, v)
+	case float32:
+		return appendFloat(b, float64(v))
+	case float64:
+		return appendFloat(b, v)
+	case string:
+		return appendText(b, v)
+	case []byte:
+		b = appendHead(b, majorBytes, uint64(len(v)))
+		return append(b, v...)
+	case time.Time:
+		return appendTime(b, v)
+	case error:
+		return appendText(b, v.Error())
+	case fmt.Stringer:
+		return appendText(b, v.String())
+	case logrus.Fields:
+		return appendMap(b, v)
+	case map[string]interface{}:
+		return appendMap(b, v)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		b = appendHead(b, majorArray, uint64(rv.Len()))
+		for i := 0; i < rv.Len(); i++ {
+			b = appendValue(b, rv.Index(i).Interface())
+		}
+		return b
+	case reflect.Map:
+		b = appendHead(b, majorMap, uint64(rv.Len()))
+		for _, k := range rv.MapKeys() {
+			b = appendText(b, fmt.Sprint(k.Interface()))
+			b = appendValue(b, rv.MapIndex(k).Interface())
+		}
+		return b
+	}
+	return appendText(b, fmt.Sprint(v))
+}
+
+func appendMap(b []byte, m map[string]interface{}) []byte {
+	b = appendHead(b, majorMap, uint64(len(m)))
+	for k, v := range m {
+		b = appendText(b, k)
+		b = appendValue(b, v)
+	}
+	return b
+}
+
+// appendHead writes the initial byte of a data item and its argument.
+func appendHead(b []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(b, major<<5|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, major<<5|24, byte(n))
+	case n <= math.MaxUint16:
+		return append(b, major<<5|25, byte(n>>8), byte(n))
+	case n <= math.MaxUint32:
+		return append(b, major<<5|26, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(b, major<<5|27, byte(n>>56), byte(n>>48), byte(n>>40), byte(n>>32), byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func appendInt(b []byte, v int64) []byte {
+	if v >= 0 {
+		return appendHead(b, majorUint, uint64(v))
+	}
+	return appendHead(b, majorNegInt, uint64(-1-v))
+}
+
+func appendFloat(b []byte, v float64) []byte {
+	bits := math.Float64bits(v)
+	return append(b, majorSimple<<5|27, byte(bits>>56), byte(bits>>48), byte(bits>>40), byte(bits>>32), byte(bits>>24), byte(bits>>16), byte(bits>>8), byte(bits))
+}
+
+func appendText(b []byte, s string) []byte {
+	b = appendHead(b, majorText, uint64(len(s)))
+	return append(b, s...)
+}
+
+func appendTime(b []byte, t time.Time) []byte {
+	b = appendHead(b, majorTag, tagEpochTime)
+	if t.Nanosecond() == 0 {
+		return appendInt(b, t.Unix())
+	}
+	return appendFloat(b, float64(t.Unix())+float64(t.Nanosecond())/1e9)
+}
@@ -0,0 +1,135 @@
+package cbor
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCBORFormatterRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	times := []time.Time{
+		time.Unix(1500000000, 0),
+		time.Unix(1500000000, 123456000),
+		time.Date(1960, 1, 1, 0, 0, 0, 500000000, time.UTC),
+		{},
+	}
+
+	var stream bytes.Buffer
+	f := &CBORFormatter{}
+	for _, tm := range times {
+		entry := logrus.WithFields(logrus.Fields{
+			"str":    "walrus",
+			"long":   strings.Repeat("x", 300),
+			"int":    -100000,
+			"small":  -5,
+			"uint":   uint64(math.MaxUint64),
+			"float":  3.14,
+			"bool":   true,
+			"nil":    nil,
+			"err":    errors.New("boom"),
+			"bytes":  []byte{1, 2, 3},
+			"list":   []int{1, 2},
+			"nested": logrus.Fields{"a": "b"},
+			"when":   tm,
+		})
+		entry.Message = "The ice breaks!"
+		entry.Level = logrus.WarnLevel
+		entry.Time = tm
+
+		b, err := f.Format(entry)
+		assert.NoError(err)
+		stream.Write(b)
+	}
+
+	dec := NewDecoder(&stream)
+	for _, tm := range times {
+		rec, err := dec.Decode()
+		assert.NoError(err)
+
+		// sub-second times are floats with microsecond precision
+		assert.True(tm.Equal(rec.Time), "%v != %v", tm, rec.Time)
+		assert.Equal(logrus.WarnLevel, rec.Level)
+		assert.Equal("The ice breaks!", rec.Message)
+		assert.Equal("walrus", rec.Data["str"])
+		assert.Equal(strings.Repeat("x", 300), rec.Data["long"])
+		assert.Equal(int64(-100000), rec.Data["int"])
+		assert.Equal(int64(-5), rec.Data["small"])
+		assert.Equal(uint64(math.MaxUint64), rec.Data["uint"])
+		assert.Equal(3.14, rec.Data["float"])
+		assert.Equal(true, rec.Data["bool"])
+		assert.Nil(rec.Data["nil"])
+		assert.Equal("boom", rec.Data["err"])
+		assert.Equal([]byte{1, 2, 3}, rec.Data["bytes"])
+		assert.Equal([]interface{}{int64(1), int64(2)}, rec.Data["list"])
+		assert.Equal(map[string]interface{}{"a": "b"}, rec.Data["nested"])
+		assert.True(tm.Equal(rec.Data["when"].(time.Time)))
+	}
+
+	_, err := dec.Decode()
+	assert.Equal(io.EOF, err)
+}
+
+func TestCBORDecoderTruncated(t *testing.T) {
+	b, err := (&CBORFormatter{}).Format(logrus.WithField("foo", "bar"))
+	assert.NoError(t, err)
+
+	_, err = NewDecoder(bytes.NewReader(b[:len(b)-2])).Decode()
+	assert.Error(t, err)
+
+	_, err = Unmarshal(b[1 : len(b)-2])
+	assert.Error(t, err)
+}
+
+func TestCBORDecoderDepth(t *testing.T) {
+	// arrays of one array, enough to overflow the stack without a limit
+	_, err := Unmarshal(bytes.Repeat([]byte{0x81}, 20<<20))
+	assert.EqualError(t, err, "cbor: nesting deeper than MaxDepth (100)")
+
+	// maps of one map, and tags of tags
+	_, err = Unmarshal(bytes.Repeat([]byte{0xa1, 0x61, 'k'}, 1000))
+	assert.EqualError(t, err, "cbor: nesting deeper than MaxDepth (100)")
+	_, err = Unmarshal(bytes.Repeat([]byte{0xc6}, 1000))
+	assert.EqualError(t, err, "cbor: nesting deeper than MaxDepth (100)")
+}
+
+var benchFields = logrus.Fields{
+	"foo":   "bar",
+	"baz":   "qux",
+	"one":   "two",
+	"three": "four",
+	"int":   42,
+	"float": 3.14,
+}
+
+func BenchmarkCBORFormatter(b *testing.B) {
+	doBenchmark(b, &CBORFormatter{})
+}
+
+func BenchmarkJSONFormatter(b *testing.B) {
+	doBenchmark(b, &logrus.JSONFormatter{})
+}
+
+func doBenchmark(b *testing.B, formatter logrus.Formatter) {
+	entry := &logrus.Entry{
+		Time:    time.Now(),
+		Level:   logrus.InfoLevel,
+		Message: "message",
+		Data:    benchFields,
+	}
+	for i := 0; i < b.N; i++ {
+		d, err := formatter.Format(entry)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.SetBytes(int64(len(d)))
+	}
+}
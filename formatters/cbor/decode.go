@@ -0,0 +1,251 @@
+package cbor
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/logrus"
+)
+
+// MaxRecordSize bounds the length prefix accepted by a Decoder.
+var MaxRecordSize uint64 = 64 << 20
+
+// MaxDepth bounds the nesting of the arrays, maps and tags of a record, the
+// record itself and its fields being 2 levels.
+var MaxDepth = 100
+
+var errShortRecord = errors.New("cbor: unexpected end of record")
+
+// Decoder reads the length delimited records written by a CBORFormatter.
+type Decoder struct {
+	r *bufio.Reader
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Decode reads the next record. It returns io.EOF when the stream ends
+// cleanly between two records. Integers are decoded as int64, or uint64 when
+// they don't fit, floats as float64, byte strings as []byte, epoch times as
+// time.Time, arrays as []interface{} and maps as map[string]interface{}.
+// Indefinite length items are not supported.
+func (d *Decoder) Decode() (*Record, error) {
+	n, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("cbor: reading record length: %v", err)
+	}
+	if n > MaxRecordSize {
+		return nil, fmt.Errorf("cbor: record of %d bytes exceeds MaxRecordSize", n)
+	}
+	p := make([]byte, n)
+	if _, err := io.ReadFull(d.r, p); err != nil {
+		return nil, errShortRecord
+	}
+	return Unmarshal(p)
+}
+
+// Unmarshal decodes a single record without its length prefix.
+func Unmarshal(p []byte) (*Record, error) {
+	dec := &decoder{b: p}
+	v, err := dec.value()
+	if err != nil {
+		return nil, err
+	}
+	a, ok := v.([]interface{})
+	if !ok || len(a) != 4 {
+		return nil, errors.New("cbor: record is not a 4 element array")
+	}
+
+	rec := &Record{}
+	if rec.Time, ok = a[0].(time.Time); !ok {
+		return nil, fmt.Errorf("cbor: invalid record time %v", a[0])
+	}
+	switch l := a[1].(type) {
+	case int64:
+		rec.Level = logrus.Level(l)
+	default:
+		return nil, fmt.Errorf("cbor: invalid record level %v", a[1])
+	}
+	if rec.Message, ok = a[2].(string); !ok {
+		return nil, fmt.Errorf("cbor: invalid record message %v", a[2])
+	}
+	data, ok := a[3].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cbor: invalid record fields %v", a[3])
+	}
+	rec.Data = logrus.Fields(data)
+	return rec, nil
+}
+
+type decoder struct {
+	b     []byte
+	off   int
+	depth int
+}
+
+func (d *decoder) next(n uint64) ([]byte, error) {
+	if uint64(len(d.b)-d.off) < n {
+		return nil, errShortRecord
+	}
+	p := d.b[d.off : d.off+int(n)]
+	d.off += int(n)
+	return p, nil
+}
+
+// head reads the initial byte of a data item and its argument.
+func (d *decoder) head() (major byte, info byte, n uint64, err error) {
+	p, err := d.next(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, info = p[0]>>5, p[0]&0x1f
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info <= 27:
+		p, err = d.next(1 << (info - 24))
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		for _, c := range p {
+			n = n<<8 | uint64(c)
+		}
+		return major, info, n, nil
+	}
+	return 0, 0, 0, fmt.Errorf("cbor: unsupported additional information %d", info)
+}
+
+func (d *decoder) value() (interface{}, error) {
+	major, info, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	if major == majorArray || major == majorMap || major == majorTag {
+		if d.depth++; d.depth > MaxDepth {
+			return nil, fmt.Errorf("cbor: nesting deeper than MaxDepth (%d)", MaxDepth)
+		}
+		defer func() { d.depth-- }()
+	}
+
+	switch major {
+	case majorUint:
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case majorNegInt:
+		if n > math.MaxInt64 {
+			return nil, fmt.Errorf("cbor: negative integer -1-%d overflows int64", n)
+		}
+		return -1 - int64(n), nil
+	case majorBytes:
+		p, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), p...), nil
+	case majorText:
+		p, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+		return string(p), nil
+	case majorArray:
+		if n > uint64(len(d.b)-d.off) {
+			return nil, errShortRecord
+		}
+		a := make([]interface{}, n)
+		for i := range a {
+			if a[i], err = d.value(); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	case majorMap:
+		if n > uint64(len(d.b)-d.off) {
+			return nil, errShortRecord
+		}
+		m := make(map[string]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			k, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+			v, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+			if s, ok := k.(string); ok {
+				m[s] = v
+			} else {
+				m[fmt.Sprint(k)] = v
+			}
+		}
+		return m, nil
+	case majorTag:
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		if n != tagEpochTime {
+			return v, nil
+		}
+		switch t := v.(type) {
+		case int64:
+			return time.Unix(t, 0), nil
+		case float64:
+			sec, frac := math.Modf(t)
+			return time.Unix(int64(sec), int64(frac*1e9)).Round(time.Microsecond), nil
+		}
+		return nil, fmt.Errorf("cbor: invalid epoch time %v", v)
+	}
+
+	// majorSimple
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		return halfToFloat(uint16(n)), nil
+	case 26:
+		return float64(math.Float32frombits(uint32(n))), nil
+	case 27:
+		return math.Float64frombits(n), nil
+	}
+	return nil, fmt.Errorf("cbor: unsupported simple value %d", n)
+}
+
+// halfToFloat converts an IEEE 754 half precision float.
+func halfToFloat(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var v float64
+	switch exp {
+	case 0:
+		v = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			v = math.Inf(1)
+		} else {
+			v = math.NaN()
+		}
+	default:
+		v = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -v
+	}
+	return v
+}
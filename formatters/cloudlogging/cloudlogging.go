@@ -0,0 +1,224 @@
+package cloudlogging
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/logrus"
+)
+
+// Special keys recognised by the Cloud Logging agents in structured payloads.
+// https://cloud.google.com/logging/docs/structured-logging#special-payload-fields
+const (
+	SourceLocationKey = "logging.googleapis.com/sourceLocation"
+	TraceKey          = "logging.googleapis.com/trace"
+	SpanIDKey         = "logging.googleapis.com/spanId"
+	TraceSampledKey   = "logging.googleapis.com/trace_sampled"
+)
+
+var (
+	severityMap = map[logrus.Level]string{
+		logrus.DebugLevel: "DEBUG",
+		logrus.InfoLevel:  "INFO",
+		logrus.WarnLevel:  "WARNING",
+		logrus.ErrorLevel: "ERROR",
+		logrus.FatalLevel: "CRITICAL",
+		logrus.PanicLevel: "ALERT",
+	}
+
+	reservedKeys = []string{
+		"severity",
+		"message",
+		"timestamp",
+		"httpRequest",
+		SourceLocationKey,
+		TraceKey,
+		SpanIDKey,
+		TraceSampledKey,
+	}
+)
+
+// HTTPRequest can be logged in the request field to report response details
+// that are not available from an `*http.Request` alone.
+type HTTPRequest struct {
+	Request      *http.Request
+	Status       int
+	ResponseSize int64
+	Latency      time.Duration
+}
+
+// CloudLoggingFormatter generates the structured JSON understood by Google
+// Cloud Logging when written to stdout or stderr of a GKE container.
+type CloudLoggingFormatter struct {
+	// ProjectID is used to build the full trace resource name
+	// "projects/<ProjectID>/traces/<trace id>". When empty the trace id is
+	// written as is.
+	ProjectID string
+
+	// RequestKey is the field holding an `*http.Request` or `*HTTPRequest`,
+	// defaults to "http_request" as in the sentry hook.
+	RequestKey string
+
+	// TraceKey, SpanKey and TraceSampledKey name the fields holding the trace
+	// context, defaulting to "trace_id", "span_id" and "trace_sampled".
+	TraceKey        string
+	SpanKey         string
+	TraceSampledKey string
+
+	// DisableSourceLocation skips filling the source location from the call
+	// stack.
+	DisableSourceLocation bool
+
+	// Sanitize the message and payload fields, unset by default.
+	Sanitize logrus.SanitizePolicy
+}
+
+type timestamp struct {
+	Seconds int64 `json:"seconds"`
+	Nanos   int   `json:"nanos"`
+}
+
+type sourceLocation struct {
+	File string `json:"file"`
+	Line string `json:"line"`
+}
+
+type httpRequest struct {
+	RequestMethod string `json:"requestMethod,omitempty"`
+	RequestURL    string `json:"requestUrl,omitempty"`
+	RequestSize   string `json:"requestSize,omitempty"`
+	Status        int    `json:"status,omitempty"`
+	ResponseSize  string `json:"responseSize,omitempty"`
+	UserAgent     string `json:"userAgent,omitempty"`
+	RemoteIP      string `json:"remoteIp,omitempty"`
+	Referer       string `json:"referer,omitempty"`
+	Latency       string `json:"latency,omitempty"`
+	Protocol      string `json:"protocol,omitempty"`
+}
+
+func (f *CloudLoggingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	entry = logrus.SanitizeEntry(entry, f.Sanitize, logrus.SanitizeNone)
+
+	requestKey := f.RequestKey
+	if requestKey == "" {
+		requestKey = "http_request"
+	}
+	traceKey := f.TraceKey
+	if traceKey == "" {
+		traceKey = "trace_id"
+	}
+	spanKey := f.SpanKey
+	if spanKey == "" {
+		spanKey = "span_id"
+	}
+	traceSampledKey := f.TraceSampledKey
+	if traceSampledKey == "" {
+		traceSampledKey = "trace_sampled"
+	}
+
+	data := make(logrus.Fields, len(entry.Data)+len(reservedKeys))
+	for k, v := range entry.Data {
+		switch k {
+		case requestKey, traceKey, spanKey, traceSampledKey:
+			continue
+		}
+		switch v := v.(type) {
+		case error:
+			data[k] = v.Error()
+		default:
+			data[k] = v
+		}
+	}
+	for _, k := range reservedKeys {
+		if v, ok := data[k]; ok {
+			data["fields."+k] = v
+			delete(data, k)
+		}
+	}
+
+	data["severity"] = severityMap[entry.Level]
+	data["message"] = entry.Message
+	data["timestamp"] = timestamp{
+		Seconds: entry.Time.Unix(),
+		Nanos:   entry.Time.Nanosecond(),
+	}
+
+	if v, ok := entry.Data[traceKey]; ok {
+		trace := fmt.Sprint(v)
+		if f.ProjectID != "" && !strings.HasPrefix(trace, "projects/") {
+			trace = "projects/" + f.ProjectID + "/traces/" + trace
+		}
+		data[TraceKey] = trace
+	}
+	if v, ok := entry.Data[spanKey]; ok {
+		data[SpanIDKey] = fmt.Sprint(v)
+	}
+	if v, ok := entry.Data[traceSampledKey].(bool); ok {
+		data[TraceSampledKey] = v
+	}
+	if r := newHTTPRequest(entry.Data[requestKey]); r != nil {
+		data["httpRequest"] = r
+	}
+
+	if !f.DisableSourceLocation {
+		if frame, ok := logrus.CallerFrame(); ok {
+			data[SourceLocationKey] = sourceLocation{File: frame.File, Line: strconv.Itoa(frame.Line)}
+		}
+	}
+
+	serialized, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal fields to JSON, %v", err)
+	}
+	return append(serialized, '\n'), nil
+}
+
+func newHTTPRequest(v interface{}) *httpRequest {
+	var hr *HTTPRequest
+	switch v := v.(type) {
+	case *http.Request:
+		hr = &HTTPRequest{Request: v}
+	case *HTTPRequest:
+		hr = v
+	case HTTPRequest:
+		hr = &v
+	}
+	if hr == nil || hr.Request == nil {
+		return nil
+	}
+
+	req := hr.Request
+	r := &httpRequest{
+		RequestMethod: req.Method,
+		Status:        hr.Status,
+		UserAgent:     req.UserAgent(),
+		RemoteIP:      req.RemoteAddr,
+		Referer:       req.Referer(),
+		Protocol:      req.Proto,
+	}
+	if req.URL != nil {
+		r.RequestURL = req.URL.String()
+		// Server side requests only carry the path in URL.
+		if req.URL.Host == "" && req.Host != "" {
+			scheme := "http"
+			if req.TLS != nil {
+				scheme = "https"
+			}
+			r.RequestURL = scheme + "://" + req.Host + req.URL.RequestURI()
+		}
+	}
+	if req.ContentLength > 0 {
+		r.RequestSize = strconv.FormatInt(req.ContentLength, 10)
+	}
+	if hr.ResponseSize > 0 {
+		r.ResponseSize = strconv.FormatInt(hr.ResponseSize, 10)
+	}
+	if hr.Latency > 0 {
+		r.Latency = strconv.FormatFloat(hr.Latency.Seconds(), 'f', 9, 64) + "s"
+	}
+	return r
+}
@@ -0,0 +1,91 @@
+package cloudlogging
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloudLoggingFormatter(t *testing.T) {
+	assert := assert.New(t)
+
+	req, _ := http.NewRequest("GET", "http://example.com/member?id=1001", nil)
+	req.Header.Set("User-Agent", "walrus/1.0")
+	req.RemoteAddr = "10.0.0.1"
+
+	f := CloudLoggingFormatter{ProjectID: "yijifu"}
+
+	entry := logrus.WithFields(logrus.Fields{
+		"http_request": &HTTPRequest{Request: req, Status: 404, Latency: 1500 * time.Millisecond},
+		"trace_id":     "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":      "00f067aa0ba902b7",
+		"severity":     "user",
+		"member":       "1001",
+	})
+	entry.Message = "member not login"
+	entry.Level = logrus.WarnLevel
+	entry.Time = time.Unix(1500000000, 123456789)
+
+	b, err := f.Format(entry)
+	assert.NoError(err)
+
+	var data map[string]interface{}
+	assert.NoError(json.Unmarshal(b, &data))
+
+	assert.Equal("WARNING", data["severity"])
+	assert.Equal("member not login", data["message"])
+	assert.Equal(map[string]interface{}{"seconds": 1500000000.0, "nanos": 123456789.0}, data["timestamp"])
+	assert.Equal("projects/yijifu/traces/4bf92f3577b34da6a3ce929d0e0e4736", data[TraceKey])
+	assert.Equal("00f067aa0ba902b7", data[SpanIDKey])
+	assert.Equal("user", data["fields.severity"])
+	assert.Equal("1001", data["member"])
+	assert.NotContains(data, "http_request")
+	assert.NotContains(data, "trace_id")
+
+	httpRequest := data["httpRequest"].(map[string]interface{})
+	assert.Equal("GET", httpRequest["requestMethod"])
+	assert.Equal("http://example.com/member?id=1001", httpRequest["requestUrl"])
+	assert.Equal(404.0, httpRequest["status"])
+	assert.Equal("walrus/1.0", httpRequest["userAgent"])
+	assert.Equal("10.0.0.1", httpRequest["remoteIp"])
+	assert.Equal("1.500000000s", httpRequest["latency"])
+
+	location := data[SourceLocationKey].(map[string]interface{})
+	assert.True(strings.HasSuffix(location["file"].(string), "cloudlogging_test.go"))
+}
+
+func TestCloudLoggingFormatterPlainRequest(t *testing.T) {
+	req, _ := http.NewRequest("POST", "http://example.com/", nil)
+
+	f := CloudLoggingFormatter{DisableSourceLocation: true}
+	b, err := f.Format(logrus.WithField("http_request", req))
+	assert.NoError(t, err)
+
+	var data map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &data))
+	assert.Equal(t, "POST", data["httpRequest"].(map[string]interface{})["requestMethod"])
+	assert.NotContains(t, data, SourceLocationKey)
+}
+
+func TestCloudLoggingFormatterCaller(t *testing.T) {
+	var buf bytes.Buffer
+	log := logrus.New()
+	log.Out = &buf
+	// wrapped by a formatter of logrus, skipped too
+	log.Formatter = logrus.ChainFormatter(&CloudLoggingFormatter{}, logrus.UTC())
+
+	_, file, line, _ := runtime.Caller(0)
+	log.Info("walrus")
+
+	var data map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &data))
+	assert.Equal(t, map[string]interface{}{"file": file, "line": strconv.Itoa(line + 1)}, data[SourceLocationKey])
+}
@@ -0,0 +1,138 @@
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/logrus"
+)
+
+// Version of the Elastic Common Schema the output conforms to.
+const Version = "1.6.0"
+
+const defaultTimestampFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// Keys of the ECS document written by the formatter. User fields using one of
+// these keys are kept under "fields.<key>".
+var reservedKeys = []string{
+	"@timestamp",
+	"ecs.version",
+	"log.level",
+	"log.logger",
+	"message",
+	"log.origin.file.name",
+	"log.origin.file.line",
+	"error.message",
+	"error.stack_trace",
+	"service.name",
+	"trace.id",
+}
+
+// ECSFormatter generates json following the Elastic Common Schema.
+// ECS reference: https://www.elastic.co/guide/en/ecs/current/index.html
+//
+// Fields from `entry.Data` are written next to the ECS fields, except for
+// the error and trace id fields which are mapped onto `error.*` and
+// `trace.id`. The entry is never modified.
+type ECSFormatter struct {
+	// ServiceName is written as "service.name" when not empty.
+	ServiceName string
+
+	// TimestampFormat sets the format used for "@timestamp", defaults to
+	// RFC 3339 with millisecond precision.
+	TimestampFormat string
+
+	// ErrorKey is the field holding the error of the entry, defaults to "error".
+	ErrorKey string
+
+	// TraceIDKey is the field holding the trace id, defaults to "trace_id".
+	TraceIDKey string
+
+	// DisableCaller skips filling "log.origin.file.*" from the call stack.
+	DisableCaller bool
+
+	// Location of "@timestamp".
+	Location *time.Location
+
+	// Sanitize, off by default, see logrus.SanitizePolicy.
+	Sanitize logrus.SanitizePolicy
+}
+
+func (f *ECSFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	entry = logrus.SanitizeEntry(entry, f.Sanitize, logrus.SanitizeNone)
+
+	errorKey := f.ErrorKey
+	if errorKey == "" {
+		errorKey = "error"
+	}
+	traceIDKey := f.TraceIDKey
+	if traceIDKey == "" {
+		traceIDKey = "trace_id"
+	}
+	timestampFormat := f.TimestampFormat
+	if timestampFormat == "" {
+		timestampFormat = defaultTimestampFormat
+	}
+
+	data := make(logrus.Fields, len(entry.Data)+len(reservedKeys))
+	for k, v := range entry.Data {
+		if k == errorKey || k == traceIDKey {
+			continue
+		}
+		switch v := v.(type) {
+		case error:
+			data[k] = v.Error()
+		default:
+			data[k] = v
+		}
+	}
+	for _, k := range reservedKeys {
+		if v, ok := data[k]; ok {
+			data["fields."+k] = v
+			delete(data, k)
+		}
+	}
+
+	t := entry.Time
+	if f.Location != nil {
+		t = t.In(f.Location)
+	}
+	data["@timestamp"] = t.Format(timestampFormat)
+	data["ecs.version"] = Version
+	data["log.level"] = strings.ToLower(entry.Level.String())
+	data["message"] = entry.Message
+
+	if f.ServiceName != "" {
+		data["service.name"] = f.ServiceName
+	}
+	if v, ok := entry.Data[traceIDKey]; ok {
+		data["trace.id"] = fmt.Sprint(v)
+	}
+	if v, ok := entry.Data[errorKey]; ok {
+		switch err := v.(type) {
+		case error:
+			data["error.message"] = err.Error()
+			// Errors carrying a stack (e.g. github.com/pkg/errors) print it with %+v.
+			if trace := fmt.Sprintf("%+v", err); trace != err.Error() {
+				data["error.stack_trace"] = trace
+			}
+		default:
+			data["error.message"] = fmt.Sprint(v)
+		}
+	}
+
+	if !f.DisableCaller {
+		if frame, ok := logrus.CallerFrame(); ok {
+			data["log.origin.file.name"] = frame.File
+			data["log.origin.file.line"] = frame.Line
+		}
+	}
+
+	serialized, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal fields to JSON, %v", err)
+	}
+	return append(serialized, '\n'), nil
+}
@@ -0,0 +1,79 @@
+package ecs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestECSFormatter(t *testing.T) {
+	assert := assert.New(t)
+
+	f := ECSFormatter{ServiceName: "member"}
+
+	fields := logrus.Fields{
+		"error":    errors.New("wild walrus"),
+		"trace_id": "4bf92f3577b34da6",
+		"message":  "def",
+		"one":      1,
+	}
+	entry := logrus.WithFields(fields)
+	entry.Message = "msg"
+	entry.Level = logrus.ErrorLevel
+
+	b, err := f.Format(entry)
+	assert.NoError(err)
+
+	var data map[string]interface{}
+	assert.NoError(json.Unmarshal(b, &data))
+
+	assert.NotEmpty(data["@timestamp"])
+	assert.Equal(Version, data["ecs.version"])
+	assert.Equal("error", data["log.level"])
+	assert.Equal("msg", data["message"])
+	assert.Equal("member", data["service.name"])
+	assert.Equal("4bf92f3577b34da6", data["trace.id"])
+	assert.Equal("wild walrus", data["error.message"])
+	assert.Equal("def", data["fields.message"])
+	assert.Equal(1.0, data["one"])
+	assert.True(strings.HasSuffix(data["log.origin.file.name"].(string), "ecs_test.go"))
+	assert.NotContains(data, "error")
+	assert.NotContains(data, "trace_id")
+
+	// the entry is left untouched
+	assert.Equal(fields, entry.Data)
+}
+
+func TestECSFormatterDisableCaller(t *testing.T) {
+	f := ECSFormatter{DisableCaller: true}
+
+	b, err := f.Format(logrus.WithField("one", 1))
+	assert.NoError(t, err)
+
+	var data map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &data))
+	assert.NotContains(t, data, "log.origin.file.name")
+	assert.NotContains(t, data, "service.name")
+}
+
+func TestECSFormatterCaller(t *testing.T) {
+	var buf bytes.Buffer
+	log := logrus.New()
+	log.Out = &buf
+	// wrapped by a formatter of logrus, skipped too
+	log.Formatter = logrus.ChainFormatter(&ECSFormatter{}, logrus.UTC())
+
+	_, file, line, _ := runtime.Caller(0)
+	log.Info("walrus")
+
+	var data map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &data))
+	assert.Equal(t, file, data["log.origin.file.name"])
+	assert.Equal(t, float64(line+1), data["log.origin.file.line"])
+}
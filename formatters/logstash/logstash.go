@@ -3,10 +3,26 @@ package logstash
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/logrus"
 )
 
+// FieldMap renames keys on the way out. Both the keys written by the
+// formatter itself ("@timestamp", "@version", "message", "level", "type")
+// and user supplied keys from `entry.Data` can be renamed, e.g.:
+//
+//  FieldMap{"message": "msg", "user_id": "user.id"}
+type FieldMap map[string]string
+
+func (f FieldMap) resolve(key string) string {
+	if k, ok := f[key]; ok {
+		return k
+	}
+	return key
+}
+
 // Formatter generates json in logstash format.
 // Logstash site: http://logstash.net/
 type LogstashFormatter struct {
@@ -14,41 +30,78 @@ type LogstashFormatter struct {
 
 	// TimestampFormat sets the format used for timestamps.
 	TimestampFormat string
+
+	// Version is written as the "@version" field, defaults to 1.
+	Version interface{}
+
+	// FieldMap allows renaming of both the default and the user fields.
+	FieldMap FieldMap
+
+	// Location of "@timestamp", e.g. time.UTC.
+	Location *time.Location
+
+	// Sanitize the message and fields before encoding, none by default.
+	Sanitize logrus.SanitizePolicy
 }
 
+// Format renders the entry without modifying `entry.Data`, so the same entry
+// can safely be passed to other formatters and hooks afterwards.
 func (f *LogstashFormatter) Format(entry *logrus.Entry) ([]byte, error) {
-	entry.Data["@version"] = 1
+	entry = logrus.SanitizeEntry(entry, f.Sanitize, logrus.SanitizeNone)
 
-	if f.TimestampFormat == "" {
-		f.TimestampFormat = logrus.DefaultTimestampFormat
+	data := make(logrus.Fields, len(entry.Data)+5)
+	for k, v := range entry.Data {
+		switch v := v.(type) {
+		case error:
+			// Otherwise errors are ignored by `encoding/json`
+			data[k] = v.Error()
+		default:
+			data[k] = v
+		}
 	}
 
-	entry.Data["@timestamp"] = entry.Time.Format(f.TimestampFormat)
-
-	// set message field
-	v, ok := entry.Data["message"]
-	if ok {
-		entry.Data["fields.message"] = v
+	// Keep user supplied values for the reserved keys under "fields.".
+	reserved := []string{"@timestamp", "@version", "message", "level"}
+	if f.Type != "" {
+		reserved = append(reserved, "type")
+	}
+	for _, k := range reserved {
+		if v, ok := data[k]; ok {
+			data["fields."+k] = v
+			delete(data, k)
+		}
 	}
-	entry.Data["message"] = entry.Message
 
-	// set level field
-	v, ok = entry.Data["level"]
-	if ok {
-		entry.Data["fields.level"] = v
+	timestampFormat := f.TimestampFormat
+	if timestampFormat == "" {
+		timestampFormat = logrus.DefaultTimestampFormat
+	}
+	version := f.Version
+	if version == nil {
+		version = 1
 	}
-	entry.Data["level"] = entry.Level.String()
 
-	// set type field
+	t := entry.Time
+	if f.Location != nil {
+		t = t.In(f.Location)
+	}
+	data["@timestamp"] = t.Format(timestampFormat)
+	data["@version"] = version
+	data["message"] = entry.Message
+	data["level"] = strings.ToLower(entry.Level.String())
 	if f.Type != "" {
-		v, ok = entry.Data["type"]
-		if ok {
-			entry.Data["fields.type"] = v
+		data["type"] = f.Type
+	}
+
+	if len(f.FieldMap) > 0 {
+		renamed := make(logrus.Fields, len(data))
+		for k, v := range data {
+			renamed[f.FieldMap.resolve(k)] = v
 		}
-		entry.Data["type"] = f.Type
+		data = renamed
 	}
 
-	serialized, err := json.Marshal(entry.Data)
+	serialized, err := json.Marshal(data)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to marshal fields to JSON, %v", err)
 	}
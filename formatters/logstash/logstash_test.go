@@ -6,6 +6,7 @@ import (
 	"github.com/logrus"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestLogstashFormatter(t *testing.T) {
@@ -50,3 +51,52 @@ func TestLogstashFormatter(t *testing.T) {
 	assert.Equal(json.Number("3.14"), data["pi"])
 	assert.Equal(true, data["bool"])
 }
+
+func TestLogstashFormatterDoesNotMutateEntry(t *testing.T) {
+	assert := assert.New(t)
+
+	lf := LogstashFormatter{Type: "abc"}
+
+	entry := logrus.WithFields(logrus.Fields{"message": "def", "one": 1})
+	entry.Message = "msg"
+
+	_, err := lf.Format(entry)
+	assert.NoError(err)
+
+	assert.Equal(logrus.Fields{"message": "def", "one": 1}, entry.Data)
+}
+
+func TestLogstashFormatterFieldMap(t *testing.T) {
+	assert := assert.New(t)
+
+	lf := LogstashFormatter{
+		Version:  "1",
+		FieldMap: FieldMap{"message": "msg", "one": "uno"},
+	}
+
+	entry := logrus.WithField("one", 1)
+	entry.Message = "hello"
+
+	b, err := lf.Format(entry)
+	assert.NoError(err)
+
+	var data map[string]interface{}
+	assert.NoError(json.Unmarshal(b, &data))
+
+	assert.Equal("hello", data["msg"])
+	assert.Equal("1", data["@version"])
+	assert.Equal(1.0, data["uno"])
+	assert.NotContains(data, "message")
+	assert.NotContains(data, "one")
+}
+
+func TestLogstashFormatterLocation(t *testing.T) {
+	lf := LogstashFormatter{Location: time.UTC}
+
+	entry := logrus.WithField("a", 1)
+	entry.Time = time.Date(2017, 7, 5, 18, 56, 30, 0, time.FixedZone("CST", 8*3600))
+
+	b, err := lf.Format(entry)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"@timestamp":"2017-07-05T10:56:30Z"`)
+}
@@ -0,0 +1,290 @@
+package msgpack
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/logrus"
+)
+
+// MaxRecordSize bounds the length prefix accepted by a Decoder.
+var MaxRecordSize uint64 = 64 << 20
+
+// MaxDepth bounds the nesting of the arrays and maps of a record, the record
+// itself and its fields being 2 levels.
+var MaxDepth = 100
+
+var errShortRecord = errors.New("msgpack: unexpected end of record")
+
+// Decoder reads the length delimited records written by a MsgpackFormatter.
+type Decoder struct {
+	r *bufio.Reader
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Decode reads the next record. It returns io.EOF when the stream ends
+// cleanly between two records. Integers are decoded as int64, or uint64 when
+// they don't fit, floats as float64, binaries as []byte, timestamps as
+// time.Time, arrays as []interface{} and maps as map[string]interface{}.
+func (d *Decoder) Decode() (*Record, error) {
+	n, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("msgpack: reading record length: %v", err)
+	}
+	if n > MaxRecordSize {
+		return nil, fmt.Errorf("msgpack: record of %d bytes exceeds MaxRecordSize", n)
+	}
+	p := make([]byte, n)
+	if _, err := io.ReadFull(d.r, p); err != nil {
+		return nil, errShortRecord
+	}
+	return Unmarshal(p)
+}
+
+// Unmarshal decodes a single record without its length prefix.
+func Unmarshal(p []byte) (*Record, error) {
+	dec := &decoder{b: p}
+	v, err := dec.value()
+	if err != nil {
+		return nil, err
+	}
+	a, ok := v.([]interface{})
+	if !ok || len(a) != 4 {
+		return nil, errors.New("msgpack: record is not a 4 element array")
+	}
+
+	rec := &Record{}
+	if rec.Time, ok = a[0].(time.Time); !ok {
+		return nil, fmt.Errorf("msgpack: invalid record time %v", a[0])
+	}
+	switch l := a[1].(type) {
+	case int64:
+		rec.Level = logrus.Level(l)
+	default:
+		return nil, fmt.Errorf("msgpack: invalid record level %v", a[1])
+	}
+	if rec.Message, ok = a[2].(string); !ok {
+		return nil, fmt.Errorf("msgpack: invalid record message %v", a[2])
+	}
+	data, ok := a[3].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("msgpack: invalid record fields %v", a[3])
+	}
+	rec.Data = logrus.Fields(data)
+	return rec, nil
+}
+
+type decoder struct {
+	b     []byte
+	off   int
+	depth int
+}
+
+func (d *decoder) next(n int) ([]byte, error) {
+	if n < 0 || len(d.b)-d.off < n {
+		return nil, errShortRecord
+	}
+	p := d.b[d.off : d.off+n]
+	d.off += n
+	return p, nil
+}
+
+func (d *decoder) uint(n int) (uint64, error) {
+	p, err := d.next(n)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range p {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+func (d *decoder) value() (interface{}, error) {
+	p, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	c := p[0]
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return d.mapValue(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return d.array(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		return d.str(int(c & 0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		p, err := d.next(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), p...), nil
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.uint(1 << (c - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.ext(int(n))
+	case 0xca:
+		v, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(v))), err
+	case 0xcb:
+		v, err := d.uint(8)
+		return math.Float64frombits(v), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		v, err := d.uint(1 << (c - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if v > math.MaxInt64 {
+			return v, nil
+		}
+		return int64(v), nil
+	case 0xd0:
+		v, err := d.uint(1)
+		return int64(int8(v)), err
+	case 0xd1:
+		v, err := d.uint(2)
+		return int64(int16(v)), err
+	case 0xd2:
+		v, err := d.uint(4)
+		return int64(int32(v)), err
+	case 0xd3:
+		v, err := d.uint(8)
+		return int64(v), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.ext(1 << (c - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.str(int(n))
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.array(int(n))
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.mapValue(int(n))
+	}
+	return nil, fmt.Errorf("msgpack: invalid format byte 0x%02x", c)
+}
+
+func (d *decoder) str(n int) (interface{}, error) {
+	p, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(p), nil
+}
+
+func (d *decoder) array(n int) (interface{}, error) {
+	if n > len(d.b)-d.off {
+		return nil, errShortRecord
+	}
+	if d.depth++; d.depth > MaxDepth {
+		return nil, fmt.Errorf("msgpack: nesting deeper than MaxDepth (%d)", MaxDepth)
+	}
+	defer func() { d.depth-- }()
+	a := make([]interface{}, n)
+	for i := range a {
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		a[i] = v
+	}
+	return a, nil
+}
+
+func (d *decoder) mapValue(n int) (interface{}, error) {
+	if n > len(d.b)-d.off {
+		return nil, errShortRecord
+	}
+	if d.depth++; d.depth > MaxDepth {
+		return nil, fmt.Errorf("msgpack: nesting deeper than MaxDepth (%d)", MaxDepth)
+	}
+	defer func() { d.depth-- }()
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := k.(string); ok {
+			m[s] = v
+		} else {
+			m[fmt.Sprint(k)] = v
+		}
+	}
+	return m, nil
+}
+
+// ext decodes an extension of n bytes of data. Only timestamps are known,
+// other extensions are returned as their raw data.
+func (d *decoder) ext(n int) (interface{}, error) {
+	p, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	typ := int8(p[0])
+	data, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	if typ != timestampExt {
+		return append([]byte(nil), data...), nil
+	}
+
+	switch n {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(data)), 0), nil
+	case 8:
+		v := binary.BigEndian.Uint64(data)
+		return time.Unix(int64(v&(1<<34-1)), int64(v>>34)), nil
+	case 12:
+		nsec := binary.BigEndian.Uint32(data)
+		sec := int64(binary.BigEndian.Uint64(data[4:]))
+		return time.Unix(sec, int64(nsec)), nil
+	}
+	return nil, fmt.Errorf("msgpack: invalid timestamp length %d", n)
+}
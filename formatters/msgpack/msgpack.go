@@ -0,0 +1,239 @@
+package msgpack
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/logrus"
+)
+
+// Extension type of the MessagePack timestamp.
+const timestampExt = -1
+
+// MsgpackFormatter encodes entries as MessagePack records, each prefixed with
+// its length as an unsigned varint. A record is the array
+//
+//	[time, level, message, fields]
+//
+// with the time as a timestamp extension, the level as a small integer and
+// the fields as a map keeping the type of their values. Use a Decoder to
+// read a stream of records back.
+// Specification: https://github.com/msgpack/msgpack/blob/master/spec.md
+type MsgpackFormatter struct {
+	// Sanitize is off by default, the length prefix keeping records apart.
+	// Set it when the records end up in a terminal or a text log.
+	Sanitize logrus.SanitizePolicy
+}
+
+// Record is a decoded entry.
+type Record struct {
+	Time    time.Time
+	Level   logrus.Level
+	Message string
+	Data    logrus.Fields
+}
+
+func (f *MsgpackFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	entry = logrus.SanitizeEntry(entry, f.Sanitize, logrus.SanitizeNone)
+
+	b := make([]byte, 0, 64+32*len(entry.Data))
+	b = appendArrayHeader(b, 4)
+	b = appendTime(b, entry.Time)
+	b = appendUint(b, uint64(entry.Level))
+	b = appendString(b, entry.Message)
+	b = appendMapHeader(b, len(entry.Data))
+	for k, v := range entry.Data {
+		b = appendString(b, k)
+		b = appendValue(b, v)
+	}
+
+	var prefix [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(prefix[:], uint64(len(b)))
+	return append(prefix[:n:n], b...), nil
+}
+
+func appendValue(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0)
+	case bool:
+		if v {
+			return append(b, 0xc3)
+		}
+		return append(b, 0xc2)
+	case int:
+		return appendInt(b, int64(v))
+	case int8:
+		return appendInt(b, int64(v))
+	case int16:
+		return appendInt(b, int64(v))
+	case int32:
+		return appendInt(b, int64(v))
+	case int64:
+		return appendInt(b, v)
+	case uint:
+		return appendUint(b, uint64(v))
+	case uint8:
+		return appendUint(b, uint64(v))
+	case uint16:
+		return appendUint(b, uint64(v))
+	case uint32:
+		return appendUint(b, uint64(v))
+	case uint64:
+		return appendUint(b, v)
+	case float32:
+		b = append(b, 0xca)
+		return appendBE32(b, math.Float32bits(v))
+	case float64:
+		b = append(b, 0xcb)
+		return appendBE64(b, math.Float64bits(v))
+	case string:
+		return appendString(b, v)
+	case []byte:
+		return appendBinary(b, v)
+	case time.Time:
+		return appendTime(b, v)
+	case error:
+		return appendString(b, v.Error())
+	case fmt.Stringer:
+		return appendString(b, v.String())
+	case logrus.Fields:
+		return appendMap(b, v)
+	case map[string]interface{}:
+		return appendMap(b, v)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		b = appendArrayHeader(b, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			b = appendValue(b, rv.Index(i).Interface())
+		}
+		return b
+	case reflect.Map:
+		b = appendMapHeader(b, rv.Len())
+		for _, k := range rv.MapKeys() {
+			b = appendString(b, fmt.Sprint(k.Interface()))
+			b = appendValue(b, rv.MapIndex(k).Interface())
+		}
+		return b
+	}
+	return appendString(b, fmt.Sprint(v))
+}
+
+func appendMap(b []byte, m map[string]interface{}) []byte {
+	b = appendMapHeader(b, len(m))
+	for k, v := range m {
+		b = appendString(b, k)
+		b = appendValue(b, v)
+	}
+	return b
+}
+
+func appendInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0:
+		return appendUint(b, uint64(v))
+	case v >= -32:
+		return append(b, byte(v))
+	case v >= math.MinInt8:
+		return append(b, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		return appendBE16(append(b, 0xd1), uint16(v))
+	case v >= math.MinInt32:
+		return appendBE32(append(b, 0xd2), uint32(v))
+	}
+	return appendBE64(append(b, 0xd3), uint64(v))
+}
+
+func appendUint(b []byte, v uint64) []byte {
+	switch {
+	case v <= math.MaxInt8:
+		return append(b, byte(v))
+	case v <= math.MaxUint8:
+		return append(b, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		return appendBE16(append(b, 0xcd), uint16(v))
+	case v <= math.MaxUint32:
+		return appendBE32(append(b, 0xce), uint32(v))
+	}
+	return appendBE64(append(b, 0xcf), v)
+}
+
+func appendString(b []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = appendBE16(append(b, 0xda), uint16(n))
+	default:
+		b = appendBE32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+func appendBinary(b []byte, p []byte) []byte {
+	n := len(p)
+	switch {
+	case n <= math.MaxUint8:
+		b = append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = appendBE16(append(b, 0xc5), uint16(n))
+	default:
+		b = appendBE32(append(b, 0xc6), uint32(n))
+	}
+	return append(b, p...)
+}
+
+func appendArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return appendBE16(append(b, 0xdc), uint16(n))
+	}
+	return appendBE32(append(b, 0xdd), uint32(n))
+}
+
+func appendMapHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return appendBE16(append(b, 0xde), uint16(n))
+	}
+	return appendBE32(append(b, 0xdf), uint32(n))
+}
+
+// appendTime uses the smallest of the timestamp 32, 64 and 96 formats.
+func appendTime(b []byte, t time.Time) []byte {
+	sec, nsec := t.Unix(), uint64(t.Nanosecond())
+	if uint64(sec)>>34 == 0 {
+		if nsec == 0 && uint64(sec)>>32 == 0 {
+			return appendBE32(append(b, 0xd6, byte(timestampExt&0xff)), uint32(sec))
+		}
+		return appendBE64(append(b, 0xd7, byte(timestampExt&0xff)), nsec<<34|uint64(sec))
+	}
+	b = append(b, 0xc7, 12, byte(timestampExt&0xff))
+	b = appendBE32(b, uint32(nsec))
+	return appendBE64(b, uint64(sec))
+}
+
+func appendBE16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendBE32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendBE64(b []byte, v uint64) []byte {
+	return append(b, byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
@@ -0,0 +1,140 @@
+package msgpack
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMsgpackFormatterRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	times := []time.Time{
+		time.Unix(1500000000, 0),                    // timestamp 32
+		time.Unix(1500000000, 123456789),            // timestamp 64
+		time.Date(2200, 1, 1, 0, 0, 0, 0, time.UTC), // timestamp 64, past timestamp 32
+		time.Date(2500, 1, 1, 0, 0, 0, 1, time.UTC), // timestamp 96
+		{},
+	}
+
+	var stream bytes.Buffer
+	f := &MsgpackFormatter{}
+	for _, tm := range times {
+		entry := logrus.WithFields(logrus.Fields{
+			"str":    "walrus",
+			"long":   strings.Repeat("x", 300),
+			"int":    -100000,
+			"small":  -5,
+			"uint":   uint64(math.MaxUint64),
+			"float":  3.14,
+			"bool":   true,
+			"nil":    nil,
+			"err":    errors.New("boom"),
+			"bytes":  []byte{1, 2, 3},
+			"list":   []int{1, 2},
+			"nested": logrus.Fields{"a": "b"},
+			"when":   tm,
+		})
+		entry.Message = "The ice breaks!"
+		entry.Level = logrus.WarnLevel
+		entry.Time = tm
+
+		b, err := f.Format(entry)
+		assert.NoError(err)
+		stream.Write(b)
+	}
+
+	dec := NewDecoder(&stream)
+	for _, tm := range times {
+		rec, err := dec.Decode()
+		assert.NoError(err)
+
+		assert.True(tm.Equal(rec.Time), "%v != %v", tm, rec.Time)
+		assert.Equal(logrus.WarnLevel, rec.Level)
+		assert.Equal("The ice breaks!", rec.Message)
+		assert.Equal("walrus", rec.Data["str"])
+		assert.Equal(strings.Repeat("x", 300), rec.Data["long"])
+		assert.Equal(int64(-100000), rec.Data["int"])
+		assert.Equal(int64(-5), rec.Data["small"])
+		assert.Equal(uint64(math.MaxUint64), rec.Data["uint"])
+		assert.Equal(3.14, rec.Data["float"])
+		assert.Equal(true, rec.Data["bool"])
+		assert.Nil(rec.Data["nil"])
+		assert.Equal("boom", rec.Data["err"])
+		assert.Equal([]byte{1, 2, 3}, rec.Data["bytes"])
+		assert.Equal([]interface{}{int64(1), int64(2)}, rec.Data["list"])
+		assert.Equal(map[string]interface{}{"a": "b"}, rec.Data["nested"])
+		assert.True(tm.Equal(rec.Data["when"].(time.Time)))
+	}
+
+	_, err := dec.Decode()
+	assert.Equal(io.EOF, err)
+}
+
+func TestMsgpackTimeFormats(t *testing.T) {
+	assert.Equal(t, byte(0xd6), appendTime(nil, time.Unix(1<<32-1, 0))[0])
+	assert.Equal(t, byte(0xd7), appendTime(nil, time.Unix(1<<32, 0))[0])
+	assert.Equal(t, byte(0xd7), appendTime(nil, time.Unix(1<<34-1, 1))[0])
+	assert.Equal(t, byte(0xc7), appendTime(nil, time.Unix(1<<34, 0))[0])
+}
+
+func TestMsgpackDecoderTruncated(t *testing.T) {
+	b, err := (&MsgpackFormatter{}).Format(logrus.WithField("foo", "bar"))
+	assert.NoError(t, err)
+
+	_, err = NewDecoder(bytes.NewReader(b[:len(b)-2])).Decode()
+	assert.Error(t, err)
+
+	_, err = Unmarshal(b[1 : len(b)-2])
+	assert.Error(t, err)
+}
+
+func TestMsgpackDecoderDepth(t *testing.T) {
+	// arrays of one array, enough to overflow the stack without a limit
+	_, err := Unmarshal(bytes.Repeat([]byte{0x91}, 20<<20))
+	assert.EqualError(t, err, "msgpack: nesting deeper than MaxDepth (100)")
+
+	// maps of one map
+	_, err = Unmarshal(bytes.Repeat([]byte{0x81, 0xa1, 'k'}, 1000))
+	assert.EqualError(t, err, "msgpack: nesting deeper than MaxDepth (100)")
+}
+
+var benchFields = logrus.Fields{
+	"foo":   "bar",
+	"baz":   "qux",
+	"one":   "two",
+	"three": "four",
+	"int":   42,
+	"float": 3.14,
+}
+
+func BenchmarkMsgpackFormatter(b *testing.B) {
+	doBenchmark(b, &MsgpackFormatter{})
+}
+
+func BenchmarkJSONFormatter(b *testing.B) {
+	doBenchmark(b, &logrus.JSONFormatter{})
+}
+
+func doBenchmark(b *testing.B, formatter logrus.Formatter) {
+	entry := &logrus.Entry{
+		Time:    time.Now(),
+		Level:   logrus.InfoLevel,
+		Message: "message",
+		Data:    benchFields,
+	}
+	for i := 0; i < b.N; i++ {
+		d, err := formatter.Format(entry)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.SetBytes(int64(len(d)))
+	}
+}
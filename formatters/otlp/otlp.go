@@ -0,0 +1,268 @@
+package otlp
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/logrus"
+)
+
+// Severity numbers of the OpenTelemetry log data model.
+// https://opentelemetry.io/docs/specs/otel/logs/data-model/#field-severitynumber
+var severityNumbers = map[logrus.Level]int{
+	logrus.DebugLevel: 5,
+	logrus.InfoLevel:  9,
+	logrus.WarnLevel:  13,
+	logrus.ErrorLevel: 17,
+	logrus.FatalLevel: 21,
+	logrus.PanicLevel: 24,
+}
+
+// OTLPFormatter renders entries as OTLP/JSON `LogRecord` objects, one per line.
+// Fields from `entry.Data` become attributes, except for the trace and span id
+// fields which are written to "traceId" and "spanId".
+//
+// To ship the records in batches, use it together with a `BatchWriter`,
+// which wraps them into `ResourceLogs`/`ScopeLogs` envelopes.
+type OTLPFormatter struct {
+	// Resource attributes, e.g. {"service.name": "member"}. Only written when
+	// Envelope is set or by a BatchWriter using this formatter.
+	Resource logrus.Fields
+
+	// ScopeName and ScopeVersion describe the instrumentation scope.
+	ScopeName    string
+	ScopeVersion string
+
+	// TraceIDKey and SpanIDKey name the fields holding the trace context,
+	// defaulting to "trace_id" and "span_id".
+	TraceIDKey string
+	SpanIDKey  string
+
+	// Envelope wraps every record in a complete export request, so each
+	// line can be posted to a collector on its own. Leave it unset when
+	// writing through a BatchWriter.
+	Envelope bool
+
+	// Sanitize the body and attributes, see logrus.SanitizePolicy.
+	Sanitize logrus.SanitizePolicy
+}
+
+// Record is the OTLP/JSON representation of a `LogRecord`.
+type Record struct {
+	TimeUnixNano         string      `json:"timeUnixNano"`
+	ObservedTimeUnixNano string      `json:"observedTimeUnixNano,omitempty"`
+	SeverityNumber       int         `json:"severityNumber"`
+	SeverityText         string      `json:"severityText"`
+	Body                 *AnyValue   `json:"body"`
+	Attributes           []*KeyValue `json:"attributes,omitempty"`
+	TraceID              string      `json:"traceId,omitempty"`
+	SpanID               string      `json:"spanId,omitempty"`
+}
+
+// KeyValue is an attribute.
+type KeyValue struct {
+	Key   string    `json:"key"`
+	Value *AnyValue `json:"value"`
+}
+
+// AnyValue holds exactly one of its members. 64 bit integers are encoded as
+// strings as required by the OTLP/JSON mapping.
+type AnyValue struct {
+	StringValue *string       `json:"stringValue,omitempty"`
+	BoolValue   *bool         `json:"boolValue,omitempty"`
+	IntValue    *string       `json:"intValue,omitempty"`
+	DoubleValue *float64      `json:"doubleValue,omitempty"`
+	BytesValue  *string       `json:"bytesValue,omitempty"`
+	ArrayValue  *ArrayValue   `json:"arrayValue,omitempty"`
+	KvlistValue *KeyValueList `json:"kvlistValue,omitempty"`
+}
+
+type ArrayValue struct {
+	Values []*AnyValue `json:"values"`
+}
+
+type KeyValueList struct {
+	Values []*KeyValue `json:"values"`
+}
+
+// Resource, ScopeLogs, ResourceLogs and LogsData form the envelope of an
+// export request.
+type Resource struct {
+	Attributes []*KeyValue `json:"attributes"`
+}
+
+type Scope struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+type ScopeLogs struct {
+	Scope      Scope             `json:"scope"`
+	LogRecords []json.RawMessage `json:"logRecords"`
+}
+
+type ResourceLogs struct {
+	Resource  Resource     `json:"resource"`
+	ScopeLogs []*ScopeLogs `json:"scopeLogs"`
+}
+
+type LogsData struct {
+	ResourceLogs []*ResourceLogs `json:"resourceLogs"`
+}
+
+func (f *OTLPFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	entry = logrus.SanitizeEntry(entry, f.Sanitize, logrus.SanitizeNone)
+
+	record, err := json.Marshal(f.record(entry))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal log record to JSON, %v", err)
+	}
+	if !f.Envelope {
+		return append(record, '\n'), nil
+	}
+
+	serialized, err := json.Marshal(f.envelope([]json.RawMessage{record}))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal log record to JSON, %v", err)
+	}
+	return append(serialized, '\n'), nil
+}
+
+func (f *OTLPFormatter) record(entry *logrus.Entry) *Record {
+	traceIDKey := f.TraceIDKey
+	if traceIDKey == "" {
+		traceIDKey = "trace_id"
+	}
+	spanIDKey := f.SpanIDKey
+	if spanIDKey == "" {
+		spanIDKey = "span_id"
+	}
+
+	r := &Record{
+		SeverityNumber: severityNumbers[entry.Level],
+		SeverityText:   entry.Level.String(),
+		Body:           anyValue(entry.Message),
+	}
+	if !entry.Time.IsZero() {
+		r.TimeUnixNano = strconv.FormatInt(entry.Time.UnixNano(), 10)
+		r.ObservedTimeUnixNano = r.TimeUnixNano
+	}
+
+	data := make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		switch k {
+		case traceIDKey:
+			r.TraceID = hexID(v)
+		case spanIDKey:
+			r.SpanID = hexID(v)
+		default:
+			data[k] = v
+		}
+	}
+	r.Attributes = attributes(data)
+	return r
+}
+
+func (f *OTLPFormatter) envelope(records []json.RawMessage) *LogsData {
+	return &LogsData{
+		ResourceLogs: []*ResourceLogs{{
+			Resource: Resource{Attributes: attributes(f.Resource)},
+			ScopeLogs: []*ScopeLogs{{
+				Scope:      Scope{Name: f.ScopeName, Version: f.ScopeVersion},
+				LogRecords: records,
+			}},
+		}},
+	}
+}
+
+// attributes converts fields to key/values sorted by key.
+func attributes(fields logrus.Fields) []*KeyValue {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]*KeyValue, 0, len(keys))
+	for _, k := range keys {
+		kvs = append(kvs, &KeyValue{Key: k, Value: anyValue(fields[k])})
+	}
+	return kvs
+}
+
+func anyValue(v interface{}) *AnyValue {
+	switch v := v.(type) {
+	case nil:
+		return &AnyValue{}
+	case string:
+		return &AnyValue{StringValue: &v}
+	case bool:
+		return &AnyValue{BoolValue: &v}
+	case int, int8, int16, int32, int64:
+		s := strconv.FormatInt(reflect.ValueOf(v).Int(), 10)
+		return &AnyValue{IntValue: &s}
+	case uint, uint8, uint16, uint32, uint64:
+		s := strconv.FormatUint(reflect.ValueOf(v).Uint(), 10)
+		return &AnyValue{IntValue: &s}
+	case float32:
+		d := float64(v)
+		return &AnyValue{DoubleValue: &d}
+	case float64:
+		return &AnyValue{DoubleValue: &v}
+	case []byte:
+		s := base64.StdEncoding.EncodeToString(v)
+		return &AnyValue{BytesValue: &s}
+	case error:
+		s := v.Error()
+		return &AnyValue{StringValue: &s}
+	case fmt.Stringer:
+		s := v.String()
+		return &AnyValue{StringValue: &s}
+	case logrus.Fields:
+		return &AnyValue{KvlistValue: &KeyValueList{Values: attributes(v)}}
+	case map[string]interface{}:
+		return &AnyValue{KvlistValue: &KeyValueList{Values: attributes(logrus.Fields(v))}}
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		values := make([]*AnyValue, rv.Len())
+		for i := range values {
+			values[i] = anyValue(rv.Index(i).Interface())
+		}
+		return &AnyValue{ArrayValue: &ArrayValue{Values: values}}
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			fields := make(logrus.Fields, rv.Len())
+			for _, k := range rv.MapKeys() {
+				fields[k.String()] = rv.MapIndex(k).Interface()
+			}
+			return &AnyValue{KvlistValue: &KeyValueList{Values: attributes(fields)}}
+		}
+	}
+
+	s := fmt.Sprint(v)
+	return &AnyValue{StringValue: &s}
+}
+
+// hexID renders trace and span ids the way OTLP/JSON expects them: as
+// lowercase hex strings.
+func hexID(v interface{}) string {
+	switch id := v.(type) {
+	case []byte:
+		return hex.EncodeToString(id)
+	case [16]byte:
+		return hex.EncodeToString(id[:])
+	case [8]byte:
+		return hex.EncodeToString(id[:])
+	case fmt.Stringer:
+		return id.String()
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,191 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// The JSON shape of the OTLP specification, decoded independently of the
+// types used to encode it.
+type specValue struct {
+	StringValue *string  `json:"stringValue"`
+	BoolValue   *bool    `json:"boolValue"`
+	IntValue    *string  `json:"intValue"`
+	DoubleValue *float64 `json:"doubleValue"`
+	KvlistValue *struct {
+		Values []specKeyValue `json:"values"`
+	} `json:"kvlistValue"`
+}
+
+type specKeyValue struct {
+	Key   string    `json:"key"`
+	Value specValue `json:"value"`
+}
+
+type specRecord struct {
+	TimeUnixNano   string         `json:"timeUnixNano"`
+	SeverityNumber int            `json:"severityNumber"`
+	SeverityText   string         `json:"severityText"`
+	Body           specValue      `json:"body"`
+	Attributes     []specKeyValue `json:"attributes"`
+	TraceID        string         `json:"traceId"`
+	SpanID         string         `json:"spanId"`
+}
+
+type specLogsData struct {
+	ResourceLogs []struct {
+		Resource struct {
+			Attributes []specKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs []struct {
+			Scope struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+			} `json:"scope"`
+			LogRecords []specRecord `json:"logRecords"`
+		} `json:"scopeLogs"`
+	} `json:"resourceLogs"`
+}
+
+func testEntry() *logrus.Entry {
+	entry := logrus.WithFields(logrus.Fields{
+		"trace_id": []byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		"span_id":  "00f067aa0ba902b7",
+		"user":     "walrus",
+		"size":     10,
+		"omg":      true,
+		"pi":       3.14,
+		"err":      errors.New("boom"),
+		"nested":   map[string]interface{}{"a": 1},
+	})
+	entry.Message = "The ice breaks!"
+	entry.Level = logrus.WarnLevel
+	entry.Time = time.Unix(1500000000, 123)
+	return entry
+}
+
+func TestOTLPFormatter(t *testing.T) {
+	assert := assert.New(t)
+
+	b, err := (&OTLPFormatter{}).Format(testEntry())
+	assert.NoError(err)
+
+	var r specRecord
+	assert.NoError(json.Unmarshal(b, &r))
+
+	assert.Equal(strconv.FormatInt(time.Unix(1500000000, 123).UnixNano(), 10), r.TimeUnixNano)
+	assert.Equal(13, r.SeverityNumber)
+	assert.Equal(logrus.WarnLevel.String(), r.SeverityText)
+	assert.Equal("The ice breaks!", *r.Body.StringValue)
+	assert.Equal("4bf92f3577b34da6a3ce929d0e0e4736", r.TraceID)
+	assert.Equal("00f067aa0ba902b7", r.SpanID)
+
+	attrs := map[string]specValue{}
+	for _, kv := range r.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	assert.Len(attrs, 6)
+	assert.Equal("walrus", *attrs["user"].StringValue)
+	assert.Equal("10", *attrs["size"].IntValue)
+	assert.Equal(true, *attrs["omg"].BoolValue)
+	assert.Equal(3.14, *attrs["pi"].DoubleValue)
+	assert.Equal("boom", *attrs["err"].StringValue)
+	assert.Equal("a", attrs["nested"].KvlistValue.Values[0].Key)
+	assert.Equal("1", *attrs["nested"].KvlistValue.Values[0].Value.IntValue)
+}
+
+func TestOTLPFormatterEnvelope(t *testing.T) {
+	assert := assert.New(t)
+
+	f := &OTLPFormatter{
+		Resource:  logrus.Fields{"service.name": "member"},
+		ScopeName: "logrus",
+		Envelope:  true,
+	}
+	b, err := f.Format(testEntry())
+	assert.NoError(err)
+
+	var data specLogsData
+	assert.NoError(json.Unmarshal(b, &data))
+
+	assert.Len(data.ResourceLogs, 1)
+	assert.Equal("service.name", data.ResourceLogs[0].Resource.Attributes[0].Key)
+	assert.Equal("member", *data.ResourceLogs[0].Resource.Attributes[0].Value.StringValue)
+	assert.Equal("logrus", data.ResourceLogs[0].ScopeLogs[0].Scope.Name)
+	assert.Len(data.ResourceLogs[0].ScopeLogs[0].LogRecords, 1)
+}
+
+func TestBatchWriter(t *testing.T) {
+	assert := assert.New(t)
+
+	var out bytes.Buffer
+	f := &OTLPFormatter{Resource: logrus.Fields{"service.name": "member"}}
+	w := NewBatchWriter(&out, f, 2)
+
+	log := logrus.NewLogger(w, f, logrus.InfoLevel, "")
+	log.Info("one")
+	log.Info("two")
+	log.Info("three")
+
+	var data specLogsData
+	assert.NoError(json.Unmarshal(out.Bytes(), &data))
+	records := data.ResourceLogs[0].ScopeLogs[0].LogRecords
+	assert.Len(records, 2)
+	assert.Equal("one", *records[0].Body.StringValue)
+	assert.Equal("two", *records[1].Body.StringValue)
+
+	out.Reset()
+	assert.NoError(w.Close())
+	assert.NoError(json.Unmarshal(out.Bytes(), &data))
+	records = data.ResourceLogs[0].ScopeLogs[0].LogRecords
+	assert.Len(records, 1)
+	assert.Equal("three", *records[0].Body.StringValue)
+
+	out.Reset()
+	assert.NoError(w.Flush())
+	assert.Equal(0, out.Len())
+}
+
+type failingWriter struct {
+	bytes.Buffer
+	fail bool
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.fail {
+		return 0, errors.New("unavailable")
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestBatchWriterError(t *testing.T) {
+	assert := assert.New(t)
+
+	out := &failingWriter{fail: true}
+	f := &OTLPFormatter{}
+	w := NewBatchWriter(out, f, 1)
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Message = "one"
+	b, err := f.Format(entry)
+	assert.NoError(err)
+	n, err := w.Write(b)
+	assert.Equal(len(b), n)
+	assert.EqualError(err, "unavailable")
+
+	// the batch is written again once Out is back
+	out.fail = false
+	assert.NoError(w.Flush())
+	var data specLogsData
+	assert.NoError(json.Unmarshal(out.Bytes(), &data))
+	records := data.ResourceLogs[0].ScopeLogs[0].LogRecords
+	assert.Len(records, 1)
+	assert.Equal("one", *records[0].Body.StringValue)
+}
@@ -0,0 +1,98 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// BatchWriter collects the records produced by an OTLPFormatter and writes
+// them to Out as `ResourceLogs`/`ScopeLogs` envelopes, using the resource and
+// scope of that formatter. Use it as `Logger.Out` with the formatter as
+// `Logger.Formatter`:
+//
+//	f := &otlp.OTLPFormatter{Resource: logrus.Fields{"service.name": "member"}}
+//	w := otlp.NewBatchWriter(conn, f, 100)
+//	log := logrus.NewLogger(w, f, logrus.InfoLevel, "")
+//	defer w.Close()
+//
+// A batch is written once MaxRecords records have been collected, or when
+// Flush or Close is called. A batch that fails to be written is kept, and
+// written again with the next records.
+type BatchWriter struct {
+	Out        io.Writer
+	Formatter  *OTLPFormatter
+	MaxRecords int
+
+	mu      sync.Mutex
+	records []json.RawMessage
+}
+
+// NewBatchWriter creates a BatchWriter, maxRecords <= 0 only writes on Flush.
+func NewBatchWriter(out io.Writer, formatter *OTLPFormatter, maxRecords int) *BatchWriter {
+	return &BatchWriter{
+		Out:        out,
+		Formatter:  formatter,
+		MaxRecords: maxRecords,
+	}
+}
+
+// Write adds one record, as produced by `OTLPFormatter.Format`, to the batch.
+// The error of writing the batch is returned with the record added.
+func (w *BatchWriter) Write(p []byte) (int, error) {
+	record := bytes.TrimSpace(p)
+	if !json.Valid(record) {
+		return 0, fmt.Errorf("otlp: not a JSON log record: %q", p)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.records = append(w.records, json.RawMessage(append([]byte(nil), record...)))
+	if w.MaxRecords > 0 && len(w.records) >= w.MaxRecords {
+		if err := w.flush(); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes the pending records, if any, as one envelope.
+func (w *BatchWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flush()
+}
+
+// Close flushes the pending records and closes Out if it is an io.Closer.
+func (w *BatchWriter) Close() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if c, ok := w.Out.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (w *BatchWriter) flush() error {
+	if len(w.records) == 0 {
+		return nil
+	}
+
+	formatter := w.Formatter
+	if formatter == nil {
+		formatter = &OTLPFormatter{}
+	}
+	serialized, err := json.Marshal(formatter.envelope(w.records))
+	if err != nil {
+		return fmt.Errorf("Failed to marshal log records to JSON, %v", err)
+	}
+	if _, err := w.Out.Write(append(serialized, '\n')); err != nil {
+		return err
+	}
+	w.records = nil
+	return nil
+}
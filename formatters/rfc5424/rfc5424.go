@@ -0,0 +1,255 @@
+package rfc5424
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/logrus"
+)
+
+// Facility of the messages, see RFC 5424 section 6.2.1. The zero value is
+// User: the constants are the numerical codes plus one.
+type Facility int
+
+const (
+	Kern Facility = iota + 1
+	User
+	Mail
+	Daemon
+	Auth
+	Syslog
+	Lpr
+	News
+	Uucp
+	Cron
+	Authpriv
+	Ftp
+	_
+	_
+	_
+	_
+	Local0
+	Local1
+	Local2
+	Local3
+	Local4
+	Local5
+	Local6
+	Local7
+)
+
+// Framing selects how messages are delimited on the wire.
+type Framing int
+
+const (
+	// NewlineFraming terminates each message with '\n', for files, stdout and
+	// TCP receivers using non-transparent framing.
+	NewlineFraming Framing = iota
+	// OctetCountingFraming prefixes each message with its length as described
+	// in RFC 6587 section 3.4.1, for TCP and TLS transports.
+	OctetCountingFraming
+	// NoFraming writes the bare message, one per UDP datagram.
+	NoFraming
+)
+
+const (
+	nilValue        = "-"
+	timestampFormat = "2006-01-02T15:04:05.000000Z07:00"
+	bom             = "\xef\xbb\xbf"
+)
+
+// code returns the numerical code of the facility.
+func (f Facility) code() int {
+	if f == 0 {
+		return int(User) - 1
+	}
+	return int(f) - 1
+}
+
+// Resolved once, rather than on each message.
+var (
+	defaultHostname, _ = os.Hostname()
+	defaultAppName     = filepath.Base(os.Args[0])
+)
+
+// Severities from RFC 5424 section 6.2.1.
+var severityMap = map[logrus.Level]int{
+	logrus.PanicLevel: 1, // alert
+	logrus.FatalLevel: 2, // critical
+	logrus.ErrorLevel: 3, // error
+	logrus.WarnLevel:  4, // warning
+	logrus.InfoLevel:  6, // informational
+	logrus.DebugLevel: 7, // debug
+}
+
+// RFC5424Formatter generates syslog messages as described in RFC 5424:
+//
+//	<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD-ID key="value"...] BOM MSG
+//
+// Fields from `entry.Data` are written as the parameters of a single
+// structured data element.
+type RFC5424Formatter struct {
+	// Facility of the messages, e.g. Local0, User by default.
+	Facility Facility
+
+	// Hostname defaults to `os.Hostname()`.
+	Hostname string
+
+	// AppName defaults to the base name of the executable.
+	AppName string
+
+	// MsgIDKey is the field holding the MSGID, defaults to "msgid".
+	MsgIDKey string
+
+	// SDID is the id of the structured data element holding the fields. It
+	// defaults to "logrus@32473", 32473 being the private enterprise number
+	// reserved for documentation; use your own registered number.
+	SDID string
+
+	// DisableBOM omits the UTF-8 byte order mark before the message.
+	DisableBOM bool
+
+	// Framing defaults to NewlineFraming.
+	Framing Framing
+
+	// Location of TIMESTAMP, e.g. time.UTC.
+	Location *time.Location
+
+	// Sanitize escapes by default, so that a message can't break the framing.
+	Sanitize logrus.SanitizePolicy
+}
+
+func (f *RFC5424Formatter) Format(entry *logrus.Entry) ([]byte, error) {
+	entry = logrus.SanitizeEntry(entry, f.Sanitize, logrus.SanitizeEscape)
+
+	msgIDKey := f.MsgIDKey
+	if msgIDKey == "" {
+		msgIDKey = "msgid"
+	}
+	sdID := f.SDID
+	if sdID == "" {
+		sdID = "logrus@32473"
+	}
+	hostname := f.Hostname
+	if hostname == "" {
+		hostname = defaultHostname
+	}
+	appName := f.AppName
+	if appName == "" {
+		appName = defaultAppName
+	}
+	msgID := ""
+	if v, ok := entry.Data[msgIDKey]; ok {
+		msgID = fmt.Sprint(v)
+	}
+
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "<%d>1 ", f.Facility.code()*8+severityMap[entry.Level])
+	if entry.Time.IsZero() {
+		b.WriteString(nilValue)
+	} else {
+		t := entry.Time
+		if f.Location != nil {
+			t = t.In(f.Location)
+		}
+		b.WriteString(t.Format(timestampFormat))
+	}
+	b.WriteByte(' ')
+	b.WriteString(headerField(hostname, 255))
+	b.WriteByte(' ')
+	b.WriteString(headerField(appName, 48))
+	b.WriteByte(' ')
+	b.WriteString(strconv.Itoa(os.Getpid()))
+	b.WriteByte(' ')
+	b.WriteString(headerField(msgID, 32))
+	b.WriteByte(' ')
+	f.appendStructuredData(b, sdID, entry.Data, msgIDKey)
+
+	if entry.Message != "" {
+		b.WriteByte(' ')
+		if !f.DisableBOM {
+			b.WriteString(bom)
+		}
+		b.WriteString(entry.Message)
+	}
+
+	switch f.Framing {
+	case OctetCountingFraming:
+		return append([]byte(strconv.Itoa(b.Len())+" "), b.Bytes()...), nil
+	case NoFraming:
+		return b.Bytes(), nil
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+func (f *RFC5424Formatter) appendStructuredData(b *bytes.Buffer, sdID string, data logrus.Fields, msgIDKey string) {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		if k != msgIDKey {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		b.WriteString(nilValue)
+		return
+	}
+	sort.Strings(keys)
+
+	b.WriteByte('[')
+	b.WriteString(sdName(sdID))
+	for _, k := range keys {
+		b.WriteByte(' ')
+		b.WriteString(sdName(k))
+		b.WriteString(`="`)
+		var value string
+		switch v := data[k].(type) {
+		case error:
+			value = v.Error()
+		default:
+			value = fmt.Sprint(v)
+		}
+		b.WriteString(sdEscaper.Replace(value))
+		b.WriteByte('"')
+	}
+	b.WriteByte(']')
+}
+
+// PARAM-VALUE escaping, RFC 5424 section 6.3.3.
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// headerField makes s a valid header field: printable US-ASCII without
+// spaces, at most max characters, or the NILVALUE when empty.
+func headerField(s string, max int) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, s)
+	if s == "" {
+		return nilValue
+	}
+	if len(s) > max {
+		s = s[:max]
+	}
+	return s
+}
+
+// sdName makes s a valid SD-NAME: a header field of at most 32 characters
+// without '=', ']' or '"'.
+func sdName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, s)
+	return headerField(s, 32)
+}
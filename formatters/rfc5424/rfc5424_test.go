@@ -0,0 +1,90 @@
+package rfc5424
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRFC5424Formatter(t *testing.T) {
+	assert := assert.New(t)
+
+	f := RFC5424Formatter{
+		Facility: Local4,
+		Hostname: "boot2docker",
+		AppName:  "member",
+	}
+
+	entry := logrus.WithFields(logrus.Fields{
+		"msgid": "LOGIN",
+		"quote": `say "hi"] \o/`,
+		"err":   errors.New("boom"),
+		"a b=c": 1,
+	})
+	entry.Message = "member not login"
+	entry.Level = logrus.ErrorLevel
+	entry.Time = time.Date(2017, 7, 5, 10, 56, 30, 123456000, time.UTC)
+
+	b, err := f.Format(entry)
+	assert.NoError(err)
+
+	expected := "<163>1 2017-07-05T10:56:30.123456Z boot2docker member " + strconv.Itoa(os.Getpid()) + " LOGIN " +
+		`[logrus@32473 a_b_c="1" err="boom" quote="say \"hi\"\] \\o/"] ` + bom + "member not login\n"
+	assert.Equal(expected, string(b))
+}
+
+func TestRFC5424FormatterNilValues(t *testing.T) {
+	f := RFC5424Formatter{Facility: User, Hostname: "host", AppName: "my app", DisableBOM: true, Framing: NoFraming}
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Message = "hello"
+	entry.Level = logrus.InfoLevel
+
+	b, err := f.Format(entry)
+	assert.NoError(t, err)
+	assert.Equal(t, "<14>1 - host my_app "+strconv.Itoa(os.Getpid())+" - - hello", string(b))
+}
+
+func TestRFC5424FormatterOctetCounting(t *testing.T) {
+	f := RFC5424Formatter{Facility: User, Hostname: "host", AppName: "app", DisableBOM: true, Framing: OctetCountingFraming}
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Message = "hello"
+	entry.Level = logrus.InfoLevel
+
+	b, err := f.Format(entry)
+	assert.NoError(t, err)
+
+	msg := "<14>1 - host app " + strconv.Itoa(os.Getpid()) + " - - hello"
+	assert.Equal(t, strconv.Itoa(len(msg))+" "+msg, string(b))
+}
+
+func TestRFC5424FormatterSanitize(t *testing.T) {
+	f := RFC5424Formatter{Facility: User, Hostname: "host", AppName: "app", DisableBOM: true}
+
+	entry := logrus.WithField("user", "a\nb")
+	entry.Message = "hello\n<14>1 - host app 1 - - forged"
+	entry.Level = logrus.InfoLevel
+
+	b, err := f.Format(entry)
+	assert.NoError(t, err)
+	assert.Equal(t, "<14>1 - host app "+strconv.Itoa(os.Getpid())+` - [logrus@32473 user="a\\nb"] `+
+		`hello\n<14>1 - host app 1 - - forged`+"\n", string(b))
+}
+
+func TestRFC5424FormatterFacility(t *testing.T) {
+	entry := logrus.WithFields(logrus.Fields{})
+	entry.Level = logrus.ErrorLevel
+
+	for facility, pri := range map[Facility]string{0: "<11>", User: "<11>", Kern: "<3>", Local7: "<187>"} {
+		f := RFC5424Formatter{Facility: facility, Hostname: "host", AppName: "app"}
+		b, err := f.Format(entry)
+		assert.NoError(t, err)
+		assert.Equal(t, pri+"1 - host app "+strconv.Itoa(os.Getpid())+" - -\n", string(b), "facility %d", facility)
+	}
+}
@@ -0,0 +1,275 @@
+package file
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Back-pressure policies of the asynchronous buffering, see
+// FileLogWriter.Backpressure.
+const (
+	BackpressureBlock = "block"
+	BackpressureDrop  = "drop"
+)
+
+// ErrClosed is returned when writing to a closed FileLogWriter.
+var ErrClosed = errors.New("hooks/file: writer closed")
+
+const defaultFlushInterval = 5 * time.Second
+
+// initAsync checks the configuration of the asynchronous buffering and starts
+// the goroutine writing the buffers.
+func (w *FileLogWriter) initAsync() error {
+	if w.BufferSize < 8*1024 {
+		w.BufferSize = 8 * 1024
+	}
+	if w.MaxPending <= 0 {
+		w.MaxPending = 4
+	}
+	if w.Backpressure == "" {
+		w.Backpressure = BackpressureBlock
+	}
+	interval, err := w.flushInterval()
+	if err != nil {
+		return err
+	}
+
+	w.buf = make([]byte, 0, w.BufferSize)
+	w.room = sync.NewCond(&w.startLock)
+	w.full = make(chan []byte, w.MaxPending)
+	w.free = make(chan []byte, w.MaxPending+1)
+	w.flushReq = make(chan chan struct{})
+	w.flushDone = make(chan struct{})
+	go w.flushLoop(interval)
+	return nil
+}
+
+// writeAsync appends msg to the buffer, ending it with a newline if asked
+// to, handing the buffer over to flushLoop when it is full. With flush, it is
+// written before writeAsync returns.
+func (w *FileLogWriter) writeAsync(msg string, newline, flush bool) error {
+	w.startLock.Lock()
+	for w.blocked {
+		w.room.Wait()
+	}
+	if w.closed {
+		w.startLock.Unlock()
+		return ErrClosed
+	}
+
+	size := len(msg)
+	if newline && (size == 0 || msg[size-1] != '\n') {
+		size++ // like log.Logger.Print
+	}
+	if len(w.buf)+size > w.BufferSize && len(w.buf) > 0 {
+		if !w.handOver() {
+			w.startLock.Unlock()
+			return nil
+		}
+	}
+	if size > w.BufferSize {
+		// written on its own, not to grow the buffers
+		big := append(make([]byte, 0, size), msg...)
+		if len(big) < size {
+			big = append(big, '\n')
+		}
+		if !w.send(big, w.Backpressure == BackpressureDrop) {
+			w.startLock.Unlock()
+			return nil
+		}
+	} else {
+		w.buf = append(w.buf, msg...)
+		if size > len(msg) {
+			w.buf = append(w.buf, '\n')
+		}
+	}
+
+	if flush && len(w.buf) > 0 {
+		w.handOver()
+	}
+	w.startLock.Unlock()
+
+	if flush {
+		w.flushBuffers()
+	}
+	return nil
+}
+
+// handOver sends the buffer to flushLoop and starts a new one, under
+// startLock. It reports false when the buffer was kept, for want of room
+// with BackpressureDrop: the message being written is dropped then.
+func (w *FileLogWriter) handOver() bool {
+	if !w.send(w.buf, w.Backpressure == BackpressureDrop) {
+		return false
+	}
+	w.buf = w.newBuffer()
+	return true
+}
+
+// send sends b to flushLoop, which owns it from then on, under startLock.
+// When MaxPending buffers wait, it drops b with drop, or waits for room,
+// releasing startLock but keeping the other writers out. The number of
+// dropped messages is written before the next buffer sent.
+func (w *FileLogWriter) send(b []byte, drop bool) bool {
+	if w.droppedSince > 0 {
+		report := fmt.Sprintf("FileLogWriter: %d messages dropped\n", w.droppedSince)
+		b = append([]byte(report), b...)
+	}
+	for {
+		select {
+		case w.full <- b:
+			w.droppedSince = 0
+			if w.blocked {
+				w.blocked = false
+				w.room.Broadcast()
+			}
+			return true
+		default:
+		}
+		if drop {
+			atomic.AddUint64(&w.dropped, 1)
+			w.droppedSince++
+			return false
+		}
+		w.blocked = true
+		w.room.Wait()
+	}
+}
+
+// newBuffer returns a written buffer, or a new one.
+func (w *FileLogWriter) newBuffer() []byte {
+	select {
+	case b := <-w.free:
+		return b
+	default:
+		return make([]byte, 0, w.BufferSize)
+	}
+}
+
+// Dropped returns the number of messages dropped with BackpressureDrop.
+func (w *FileLogWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+// flushLoop writes the buffers handed over, in order, and the buffer being
+// filled every interval or when asked to, until full is closed.
+func (w *FileLogWriter) flushLoop(interval time.Duration) {
+	defer close(w.flushDone)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case b, ok := <-w.full:
+			if !ok {
+				return
+			}
+			w.madeRoom()
+			w.writeBuffer(b)
+		case <-ticker.C:
+			w.drain()
+		case done := <-w.flushReq:
+			w.drain()
+			close(done)
+		}
+	}
+}
+
+// drain writes the buffers handed over, then takes the buffer being filled
+// and writes it.
+func (w *FileLogWriter) drain() {
+	for {
+		select {
+		case b, ok := <-w.full:
+			if ok {
+				w.madeRoom()
+				w.writeBuffer(b)
+				continue
+			}
+		default:
+		}
+
+		w.startLock.Lock()
+		if len(w.full) > 0 || w.blocked {
+			// handed over meanwhile, to be written first
+			w.startLock.Unlock()
+			continue
+		}
+		b := w.buf
+		if len(b) > 0 {
+			w.buf = w.newBuffer()
+		}
+		w.startLock.Unlock()
+
+		if len(b) > 0 {
+			w.writeBuffer(b)
+		}
+		return
+	}
+}
+
+// madeRoom wakes up the writer waiting for room in full, if any.
+func (w *FileLogWriter) madeRoom() {
+	w.startLock.Lock()
+	w.room.Broadcast()
+	w.startLock.Unlock()
+}
+
+// writeBuffer writes b to the file, then gives it back for reuse.
+func (w *FileLogWriter) writeBuffer(b []byte) {
+	if w.Shared {
+		if _, err := w.writeShared(b); err != nil {
+			fmt.Fprintf(os.Stderr, "FileLogWriter(%q): writing %d bytes: %s\n", w.Filename, len(b), err)
+		}
+	} else {
+		if _, err := w.mw.Write(b); err != nil {
+			fmt.Fprintf(os.Stderr, "FileLogWriter(%q): writing %d bytes: %s\n", w.Filename, len(b), err)
+		}
+		// 先将所有的日志数据刷新到文本,然后再判断并打开新的日志文件;
+		w.docheck(len(b), bytes.Count(b, []byte{'\n'}))
+	}
+
+	if cap(b) == w.BufferSize {
+		select {
+		case w.free <- b[:0]:
+		default:
+		}
+	}
+}
+
+// flushBuffers waits for flushLoop to write everything buffered so far.
+func (w *FileLogWriter) flushBuffers() {
+	done := make(chan struct{})
+	select {
+	case w.flushReq <- done:
+		<-done
+	case <-w.flushDone:
+	}
+}
+
+// closeAsync stops accepting messages, writes everything buffered and stops
+// flushLoop.
+func (w *FileLogWriter) closeAsync() {
+	w.startLock.Lock()
+	for w.blocked {
+		w.room.Wait()
+	}
+	if w.closed {
+		w.startLock.Unlock()
+		return
+	}
+	w.closed = true
+	if len(w.buf) > 0 || w.droppedSince > 0 {
+		w.send(w.buf, false)
+		w.buf = nil
+	}
+	close(w.full)
+	w.startLock.Unlock()
+
+	<-w.flushDone
+}
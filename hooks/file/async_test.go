@@ -0,0 +1,264 @@
+package file
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// readLines reads the rotated files then the file written to, in order.
+func readLines(t *testing.T, w *FileLogWriter) []string {
+	segments, err := w.Segments()
+	assert.NoError(t, err)
+	var lines []string
+	for _, path := range append(segmentPaths(segments), w.Filename) {
+		f, err := os.Open(path)
+		assert.NoError(t, err)
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		f.Close()
+	}
+	return lines
+}
+
+func segmentPaths(segments []Segment) []string {
+	var paths []string
+	for _, s := range segments {
+		paths = append(paths, s.Path)
+	}
+	return paths
+}
+
+func TestAsyncStress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := newTestWriter(t, dir, `,"asyncbuffer":true,"maxpending":2,"flushinterval":"5ms","maxsize":65536,"maxdays":0`)
+
+	const writers, messages = 16, 2000
+	var wg sync.WaitGroup
+	for g := 0; g < writers; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < messages; i++ {
+				level := 4
+				if i%500 == 0 {
+					level = 2
+				}
+				assert.NoError(t, w.WriteMsg(fmt.Sprintf("g%02d %05d", g, i), level))
+			}
+		}(g)
+	}
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				w.Flush()
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+	wg.Wait()
+	close(stop)
+	assert.NoError(t, w.Close())
+
+	next := make([]int, writers)
+	lines := readLines(t, w)
+	for _, line := range lines {
+		var g, i int
+		_, err := fmt.Sscanf(line, "g%02d %05d", &g, &i)
+		if assert.NoError(t, err, line) {
+			assert.Equal(t, next[g], i, "writer %d", g)
+			next[g] = i + 1
+		}
+	}
+	assert.Len(t, lines, writers*messages)
+
+	segments, err := w.Segments()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, segments)
+}
+
+func TestAsyncHookSequence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := newTestWriter(t, dir, `,"asyncbuffer":true,"flushlevel":2,"maxsize":4096,"maxdays":0,"level":5`)
+	log := logrus.New()
+	log.Out = ioutil.Discard
+	log.Sequencer = &logrus.Sequencer{StartID: "run1"}
+	hook := NewWriterHook(w, "[%L] %M %F")
+	log.Hooks.Add(hook)
+
+	const writers, messages = 8, 250
+	var wg sync.WaitGroup
+	for g := 0; g < writers; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < messages; i++ {
+				log.WithField("g", g).Info("hi")
+			}
+		}(g)
+	}
+	wg.Wait()
+	assert.NoError(t, hook.Close())
+
+	lines := readLines(t, w)
+	assert.Regexp(t, `^\[INFO\] hi g=\d seq=\d+ start_id=run1$`, lines[0])
+	report, err := (&logrus.SequenceChecker{}).Check(strings.NewReader(strings.Join(lines, "\n")))
+	assert.NoError(t, err)
+	assert.Equal(t, writers*messages, report.Entries)
+	// reordered by the concurrent writers at most, never lost
+	for _, issue := range report.Issues {
+		assert.NotEqual(t, logrus.SequenceGap, issue.Kind, "%s", issue)
+		assert.NotEqual(t, logrus.SequenceDuplicate, issue.Kind, "%s", issue)
+	}
+}
+
+func TestAsyncFlushLevel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := newTestWriter(t, dir, `,"asyncbuffer":true,"flushinterval":"1h"`)
+	defer w.Close()
+
+	assert.NoError(t, w.WriteMsg("info", 4))
+	b, err := ioutil.ReadFile(w.Filename)
+	assert.NoError(t, err)
+	assert.Empty(t, string(b))
+
+	assert.NoError(t, w.WriteMsg("error\n", 2))
+	b, err = ioutil.ReadFile(w.Filename)
+	assert.NoError(t, err)
+	assert.Equal(t, "info\nerror\n", string(b))
+}
+
+func TestAsyncFlushInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := newTestWriter(t, dir, `,"asyncbuffer":true,"flushinterval":"10ms"`)
+	defer w.Close()
+
+	assert.NoError(t, w.WriteMsg("info", 4))
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if b, _ := ioutil.ReadFile(w.Filename); string(b) == "info\n" {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Error("not flushed")
+}
+
+func TestAsyncOversized(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := newTestWriter(t, dir, `,"asyncbuffer":true,"flushinterval":"1h"`)
+	big := strings.Repeat("x", 20000)
+	assert.NoError(t, w.WriteMsg("before", 4))
+	assert.NoError(t, w.WriteMsg(big, 4))
+	assert.NoError(t, w.WriteMsg("after", 4))
+	assert.NoError(t, w.Close())
+
+	assert.Equal(t, []string{"before", big, "after"}, readLines(t, w))
+}
+
+func TestAsyncDrop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := newTestWriter(t, dir, `,"asyncbuffer":true,"flushinterval":"1h","maxpending":1,"backpressure":"drop"`)
+
+	// the file is stuck: one buffer being written, one waiting
+	w.mw.Lock()
+	msg := strings.Repeat("m", 1023)
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, w.WriteMsg(msg, 4))
+	}
+	dropped := int(w.Dropped())
+	assert.True(t, dropped > 0)
+	w.mw.Unlock()
+	assert.NoError(t, w.Close())
+
+	lines := readLines(t, w)
+	kept := 0
+	for _, line := range lines {
+		if line == msg {
+			kept++
+		} else {
+			assert.Regexp(t, `^FileLogWriter: \d+ messages dropped$`, line)
+		}
+	}
+	assert.Equal(t, 100-dropped, kept)
+}
+
+func TestAsyncBlock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := newTestWriter(t, dir, `,"asyncbuffer":true,"flushinterval":"1h","maxpending":1`)
+
+	w.mw.Lock()
+	done := make(chan struct{})
+	msg := strings.Repeat("m", 1023)
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			assert.NoError(t, w.WriteMsg(msg, 4))
+		}
+	}()
+	select {
+	case <-done:
+		t.Fatal("not blocked")
+	case <-time.After(100 * time.Millisecond):
+	}
+	w.mw.Unlock()
+	<-done
+	assert.NoError(t, w.Close())
+
+	assert.Len(t, readLines(t, w), 100)
+	assert.Equal(t, uint64(0), w.Dropped())
+}
+
+func TestWriteAfterClose(t *testing.T) {
+	for _, config := range []string{"", `,"asyncbuffer":true`} {
+		dir, err := ioutil.TempDir("", "logrus-file")
+		assert.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		w := newTestWriter(t, dir, config)
+		assert.NoError(t, w.WriteMsg("before", 4))
+		assert.NoError(t, w.Close())
+		assert.Equal(t, ErrClosed, w.WriteMsg("after", 4))
+
+		b, err := ioutil.ReadFile(filepath.Join(dir, "app.log"))
+		assert.NoError(t, err)
+		assert.Equal(t, "before\n", string(b))
+	}
+}
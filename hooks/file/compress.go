@@ -0,0 +1,194 @@
+package file
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Compression methods of the rotated files, see FileLogWriter.Compress.
+const (
+	CompressGzip = "gzip"
+	CompressZlib = "zlib"
+)
+
+// compressSuffixes are the suffixes of the compressed rotated files.
+var compressSuffixes = map[string]string{
+	CompressGzip: ".gz",
+	CompressZlib: ".zz",
+}
+
+// tmpSuffix marks a compressed file being written, renamed once complete.
+const tmpSuffix = ".tmp"
+
+// segmentExists reports whether the rotated file name exists, compressed or
+// not.
+func segmentExists(name string) bool {
+	if _, err := os.Lstat(name); err == nil {
+		return true
+	}
+	for _, suffix := range compressSuffixes {
+		if _, err := os.Lstat(name + suffix); err == nil {
+			return true
+		}
+		if _, err := os.Lstat(name + suffix + tmpSuffix); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// compressInBackground compresses the rotated file name, if compression is
+// on, without blocking the writes, then applies the retention.
+func (w *FileLogWriter) compressInBackground(name string) {
+	if w.Compress == "" {
+		return
+	}
+	w.runInBackground(func() {
+		if err := w.compressSegment(name); err != nil {
+			fmt.Fprintf(os.Stderr, "FileLogWriter(%q): %s\n", w.Filename, err)
+		}
+		w.deleteOldLog()
+	})
+}
+
+// runInBackground runs f without blocking the writes, one compression or
+// retention at a time: the retention never sees a file being compressed.
+// Close waits for it to complete.
+func (w *FileLogWriter) runInBackground(f func()) {
+	w.background.Add(1)
+	go func() {
+		defer w.background.Done()
+		w.backgroundLock.Lock()
+		defer w.backgroundLock.Unlock()
+		f()
+	}()
+}
+
+// compressSegment compresses the rotated file name to a temporary file,
+// renames it once complete, then removes name. The compressed file keeps the
+// modification time of name, so that Maxdays applies the same to both.
+func (w *FileLogWriter) compressSegment(name string) error {
+	suffix := compressSuffixes[w.Compress]
+	tmp := name + suffix + tmpSuffix
+
+	src, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("Compress: %s", err)
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("Compress: %s", err)
+	}
+
+	dst, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("Compress: %s", err)
+	}
+	if err = w.compressTo(dst, src, info); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("Compress %s: %s", name, err)
+	}
+	if err = dst.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("Compress %s: %s", name, err)
+	}
+
+	os.Chtimes(tmp, info.ModTime(), info.ModTime())
+	if err = os.Rename(tmp, name+suffix); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("Compress: %s", err)
+	}
+	if err = os.Remove(name); err != nil {
+		return fmt.Errorf("Compress: %s", err)
+	}
+	return nil
+}
+
+func (w *FileLogWriter) compressTo(dst *os.File, src io.Reader, info os.FileInfo) error {
+	var zw io.WriteCloser
+	switch w.Compress {
+	case CompressGzip:
+		gw, err := gzip.NewWriterLevel(dst, w.CompressLevel)
+		if err != nil {
+			return err
+		}
+		gw.Name = info.Name()
+		gw.ModTime = info.ModTime()
+		zw = gw
+	case CompressZlib:
+		var err error
+		if zw, err = zlib.NewWriterLevel(dst, w.CompressLevel); err != nil {
+			return err
+		}
+	}
+
+	if _, err := io.Copy(zw, src); err != nil {
+		zw.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return dst.Sync()
+}
+
+// recoverSegments completes the work of a process stopped while compressing:
+// temporary files are removed and compressed again, and rotated files already
+// compressed are removed. Remaining uncompressed rotated files are compressed
+// when compression is on.
+func (w *FileLogWriter) recoverSegments() error {
+	dir := filepath.Dir(w.Filename)
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("hooks/file: %s", err)
+	}
+
+	names := make(map[string]bool, len(infos))
+	for _, info := range infos {
+		names[info.Name()] = true
+	}
+
+	for _, info := range infos {
+		name, ok := w.names().parse(info.Name())
+		path := filepath.Join(dir, info.Name())
+		if !ok || info.IsDir() || path == w.current {
+			continue
+		}
+		switch {
+		case name.tmp:
+			source := strings.TrimSuffix(info.Name(), name.suffix+tmpSuffix)
+			if !names[source] {
+				fmt.Fprintf(os.Stderr, "FileLogWriter(%q): %s is incomplete and has no source, left as is\n", w.Filename, path)
+				continue
+			}
+			os.Remove(path)
+		case name.suffix == "":
+			if compressedExists(names, info.Name()) {
+				os.Remove(path)
+			}
+		}
+	}
+
+	if w.Compress == "" {
+		return nil
+	}
+	for _, info := range infos {
+		name, ok := w.names().parse(info.Name())
+		path := filepath.Join(dir, info.Name())
+		if !ok || info.IsDir() || path == w.current || name.suffix != "" || name.tmp {
+			continue
+		}
+		if _, err := os.Lstat(path); err == nil {
+			w.compressInBackground(path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package file
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestWriter(t *testing.T, dir, config string) *FileLogWriter {
+	w := NewFileWriter().(*FileLogWriter)
+	filename := filepath.Join(dir, "app.log")
+	err := w.Init(fmt.Sprintf(`{"filename":%q,"daily":false%s}`, filename, config))
+	assert.NoError(t, err)
+	return w
+}
+
+func listDir(t *testing.T, dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readCompressed(t *testing.T, path string, open func(io.Reader) (io.ReadCloser, error)) string {
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	r, err := open(f)
+	assert.NoError(t, err)
+	b, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	return string(b)
+}
+
+func openGzip(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) }
+
+func TestCompressRotated(t *testing.T) {
+	for _, c := range []struct {
+		compress, suffix string
+		open             func(io.Reader) (io.ReadCloser, error)
+	}{
+		{CompressGzip, ".gz", openGzip},
+		{CompressZlib, ".zz", zlib.NewReader},
+	} {
+		dir, err := ioutil.TempDir("", "logrus-file")
+		assert.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		w := newTestWriter(t, dir, fmt.Sprintf(`,"compress":%q`, c.compress))
+		assert.NoError(t, w.WriteMsg("first", 4))
+		assert.NoError(t, w.DoRotate())
+		assert.NoError(t, w.WriteMsg("second", 4))
+		w.Destroy()
+
+		segment := "app.log." + time.Now().Format("2006-01-02") + ".001"
+		assert.Equal(t, []string{"app.log", segment + c.suffix}, listDir(t, dir))
+		assert.Equal(t, "first\n", readCompressed(t, filepath.Join(dir, segment+c.suffix), c.open))
+	}
+}
+
+func TestCompressKeepsModTime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	segment := filepath.Join(dir, "app.log.2017-07-05.001")
+	assert.NoError(t, ioutil.WriteFile(segment, []byte("old\n"), 0660))
+	old := time.Date(2017, 7, 5, 10, 56, 30, 0, time.UTC)
+	assert.NoError(t, os.Chtimes(segment, old, old))
+
+	w := newTestWriter(t, dir, `,"compress":"gzip","maxdays":0`)
+	w.Destroy()
+
+	info, err := os.Stat(segment + ".gz")
+	if assert.NoError(t, err) {
+		assert.True(t, info.ModTime().Equal(old))
+	}
+
+	// expired compressed files are removed like the others
+	w.Maxdays = 1
+	w.deleteOldLog()
+	assert.Equal(t, []string{"app.log"}, listDir(t, dir))
+}
+
+func TestRecoverSegments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0660))
+	}
+	// stopped while compressing
+	write("app.log.2017-07-05.001", "one\n")
+	write("app.log.2017-07-05.001.gz.tmp", "partial")
+	// stopped before removing the compressed file
+	write("app.log.2017-07-05.002", "two\n")
+	write("app.log.2017-07-05.002.gz", "complete")
+	// never compressed
+	write("app.log.2017-07-05.003", "three\n")
+	// not a rotated file
+	write("app.log.old", "other\n")
+
+	w := newTestWriter(t, dir, `,"compress":"gzip","maxdays":0`)
+	w.Destroy()
+
+	assert.Equal(t, []string{
+		"app.log",
+		"app.log.2017-07-05.001.gz",
+		"app.log.2017-07-05.002.gz",
+		"app.log.2017-07-05.003.gz",
+		"app.log.old",
+	}, listDir(t, dir))
+	assert.Equal(t, "one\n", readCompressed(t, filepath.Join(dir, "app.log.2017-07-05.001.gz"), openGzip))
+	assert.Equal(t, "three\n", readCompressed(t, filepath.Join(dir, "app.log.2017-07-05.003.gz"), openGzip))
+}
+
+func TestRotateSkipsCompressedNumbers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	segment := "app.log." + time.Now().Format("2006-01-02") + ".001"
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, segment+".gz"), nil, 0660))
+
+	w := newTestWriter(t, dir, "")
+	assert.NoError(t, w.WriteMsg("first", 4))
+	assert.NoError(t, w.DoRotate())
+	w.Destroy()
+
+	assert.Contains(t, listDir(t, dir), "app.log."+time.Now().Format("2006-01-02")+".002")
+}
+
+func TestUnknownCompress(t *testing.T) {
+	w := NewFileWriter()
+	err := w.Init(`{"filename":"app.log","compress":"lz4"}`)
+	assert.EqualError(t, err, `hooks/file: unknown compress "lz4", use "gzip" or "zlib"`)
+}
@@ -0,0 +1,365 @@
+package file
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is the configuration of a FileLogWriter, see DefaultConfig. The
+// JSON keys are the lowercase names of the fields.
+//
+// A Config not started from DefaultConfig, such as Config{Filename:
+// "app.log"}, gets FileMode, DirMode, Level, FlushLevel and CompressLevel
+// from DefaultConfig in OpenWriter and NewHook when they are zero. The other
+// zero values are settings of their own, such as no daily rotation and no
+// limit. The zero values of a Config started from DefaultConfig, as the JSON
+// configurations are, are all kept.
+type Config struct {
+	// The opened file
+	Filename string `json:"filename"`
+
+	// FileMode is the permission of the files created, 0660 by default.
+	// DirMode is the permission of the directories created for Filename and
+	// LockFile, 0755 by default. The umask of the process applies.
+	FileMode Perm `json:"filemode"`
+	DirMode  Perm `json:"dirmode"`
+
+	Maxlines int `json:"maxlines"` // ignored with Shared
+
+	// Rotate at size
+	Maxsize int `json:"maxsize"`
+
+	// Rotate daily, at midnight, unless RotateInterval or RotateSchedule is
+	// set.
+	Daily   bool  `json:"daily"`
+	Maxdays int64 `json:"maxdays"` // 0: 不按天数删除
+
+	// RotateInterval rotates every interval, aligned on the wall clock:
+	// "15m" rotates at :00, :15, :30 and :45, "24h" at midnight and "168h"
+	// on Mondays at midnight. "hourly", "daily" and "weekly" are accepted.
+	RotateInterval string `json:"rotateinterval"`
+	// RotateSchedule rotates on a cron schedule instead, "minute hour
+	// day-of-month month day-of-week", e.g. "0 */6 * * *" or "@weekly".
+	RotateSchedule string `json:"rotateschedule"`
+	// Timezone of the rotation times and of the rotated names, e.g.
+	// "Europe/Paris", defaults to the local one.
+	Timezone string `json:"timezone"`
+
+	// Retention of the rotated files, besides Maxdays, see Expired.
+	MaxBackups   int   `json:"maxbackups"`   // 保留的最大文件个数,默认: 0,不限制
+	MaxTotalSize int64 `json:"maxtotalsize"` // 保留文件的最大总字节数,默认: 0,不限制
+
+	Rotate bool `json:"rotate"`
+
+	// RotateName names the rotated files in the directory of Filename, with
+	// strftime verbs (%Y %y %m %b %d %j %H %M %S) or Go time layout elements
+	// (2006 06 01 Jan 02 002 15 04 05), and the counter %N, e.g.
+	// "app-%Y%m%d-%H.log" or "app.2006-01-02.%N.log". Without %N, a ".N"
+	// counter is appended when a name is taken. Defaults to Filename +
+	// ".%Y-%m-%d.%N".
+	RotateName string `json:"rotatename"`
+
+	// Direct writes to the files named by RotateName directly, instead of
+	// writing to Filename and renaming it when rotating. Only the directory
+	// of Filename is used then. The files are rotated when their name
+	// changes too, e.g. hourly with %H.
+	Direct bool `json:"direct"`
+
+	// Symlink, when set, is a symlink kept pointing to the file written to,
+	// e.g. Filename with Direct.
+	Symlink string `json:"symlink"`
+
+	// ReopenCheck is how often the path of the file written to is checked,
+	// when writing, for having been renamed, removed or truncated by another
+	// process such as logrotate, e.g. "10s". The file is opened again when
+	// renamed or removed. Defaults to "1s", "0" disables it.
+	ReopenCheck string `json:"reopencheck"`
+	// ReopenSignal reopens the file on "SIGHUP", "SIGUSR1" or "SIGUSR2",
+	// see Reopen. Not supported on Windows.
+	ReopenSignal string `json:"reopensignal"`
+	// CopyTruncate rotates by copying the file, then truncating it, instead
+	// of renaming it, for the processes having it open too. Messages written
+	// meanwhile by those are lost.
+	CopyTruncate bool `json:"copytruncate"`
+
+	// Shared coordinates the processes writing to Filename: they rotate it
+	// in turn, holding an advisory lock on LockFile, each rotating when the
+	// size of the file, written by all of them, reaches Maxsize. Every
+	// message lands whole in a single file. Maxlines is ignored, and Direct
+	// is not supported. Not supported on Windows.
+	Shared   bool   `json:"shared"`
+	LockFile string `json:"lockfile"` // 默认: Filename + ".lock"
+
+	// Compress the rotated files in the background, with "gzip" or "zlib",
+	// into name.gz or name.zz. Off by default.
+	Compress      string `json:"compress"`
+	CompressLevel int    `json:"compresslevel"` // 默认: gzip.DefaultCompression
+
+	// Level: the messages more verbose are not written, from 0, panic, to
+	// 5, debug. Defaults to 4, info.
+	Level int `json:"level"`
+
+	// 18/09/2017新增:
+	//   日志缓存模块,批量刷新到磁盘;
+	//   Add by 164776775@qq.com WeChat:164776775 谷子慧
+	AsyncBuffer bool `json:"asyncbuffer"` // 默认: false.
+	BufferSize  int  `json:"buffersize"`  // 缓冲区大小,默认: 8KB
+
+	// FlushInterval writes the buffer at least that often, e.g. "1s".
+	// Defaults to "5s".
+	FlushInterval string `json:"flushinterval"`
+	// FlushLevel: the messages of that level or more severe are written,
+	// after everything buffered before them, before WriteMsg returns.
+	// Defaults to 2, the error level.
+	FlushLevel int `json:"flushlevel"`
+	// MaxPending is the number of full buffers waiting to be written,
+	// defaults to 4.
+	MaxPending int `json:"maxpending"`
+	// Backpressure, when MaxPending buffers wait: "block" (default) blocks
+	// the messages until there is room, "drop" drops them, see Dropped.
+	Backpressure string `json:"backpressure"`
+
+	// PrintFormat of the messages written by the hooks, see NewHook.
+	PrintFormat string `json:"printformat"`
+
+	defaults bool // started from DefaultConfig
+}
+
+// Perm is the permission of a file or directory. In JSON, it is either a
+// string of octal digits, such as "0640", or a number.
+type Perm os.FileMode
+
+func (p *Perm) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		var n uint32
+		if err := json.Unmarshal(b, &n); err != nil {
+			return fmt.Errorf("invalid permission %s, want octal digits such as \"0640\"", b)
+		}
+		*p = Perm(n)
+		return nil
+	}
+	n, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return fmt.Errorf("invalid permission %q, want octal digits such as \"0640\"", s)
+	}
+	*p = Perm(n)
+	return nil
+}
+
+func (p Perm) String() string {
+	return fmt.Sprintf("%#o", uint32(p))
+}
+
+// DefaultConfig returns the default configuration, to be completed with
+// Filename at least.
+func DefaultConfig() Config {
+	return Config{
+		FileMode:      0660,
+		DirMode:       0755,
+		Maxlines:      1000000,
+		Maxsize:       1 << 28, //256 MB
+		Daily:         true,
+		Maxdays:       7,
+		Rotate:        true,
+		Level:         4, // info level.
+		AsyncBuffer:   false,
+		BufferSize:    8 * 1024,
+		FlushLevel:    2, // error level
+		CompressLevel: gzip.DefaultCompression,
+		defaults:      true,
+	}
+}
+
+// withDefaults returns c with its zero FileMode, DirMode, Level, FlushLevel
+// and CompressLevel taken from DefaultConfig, unless c was started from it.
+func (c Config) withDefaults() Config {
+	if c.defaults {
+		return c
+	}
+	def := DefaultConfig()
+	if c.FileMode == 0 {
+		c.FileMode = def.FileMode
+	}
+	if c.DirMode == 0 {
+		c.DirMode = def.DirMode
+	}
+	if c.Level == 0 {
+		c.Level = def.Level
+	}
+	if c.FlushLevel == 0 {
+		c.FlushLevel = def.FlushLevel
+	}
+	if c.CompressLevel == 0 {
+		c.CompressLevel = def.CompressLevel
+	}
+	c.defaults = true
+	return c
+}
+
+// ParseConfig returns the configuration in JSON over DefaultConfig, checked
+// with Validate. Unknown keys are rejected, e.g. "daliy" for "daily".
+func ParseConfig(jsonConfig string) (Config, error) {
+	cfg := DefaultConfig()
+	if err := decodeConfig(jsonConfig, &cfg); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// decodeConfig decodes the configuration in JSON over cfg, rejecting
+// unknown keys.
+func decodeConfig(jsonConfig string, cfg *Config) error {
+	dec := json.NewDecoder(strings.NewReader(jsonConfig))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return fmt.Errorf("hooks/file: invalid config: %s", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("hooks/file: invalid config: data after the JSON object")
+	}
+	return nil
+}
+
+// Validate checks the configuration, returning an error naming the invalid
+// setting.
+func (c *Config) Validate() error {
+	if c.Filename == "" {
+		return errors.New("hooks/file: filename is required")
+	}
+	if c.FileMode&^0777 != 0 || c.FileMode&0200 == 0 {
+		return fmt.Errorf("hooks/file: invalid filemode %s, the owner must be able to write", c.FileMode)
+	}
+	if c.DirMode&^0777 != 0 || c.DirMode&0300 != 0300 {
+		return fmt.Errorf("hooks/file: invalid dirmode %s, the owner must be able to write and search", c.DirMode)
+	}
+	for _, limit := range []struct {
+		name  string
+		value int64
+	}{
+		{"maxlines", int64(c.Maxlines)},
+		{"maxsize", int64(c.Maxsize)},
+		{"maxdays", c.Maxdays},
+		{"maxbackups", int64(c.MaxBackups)},
+		{"maxtotalsize", c.MaxTotalSize},
+		{"buffersize", int64(c.BufferSize)},
+		{"maxpending", int64(c.MaxPending)},
+	} {
+		if limit.value < 0 {
+			return fmt.Errorf("hooks/file: invalid %s %d, negative", limit.name, limit.value)
+		}
+	}
+	if c.Level < 0 || c.Level > 5 {
+		return fmt.Errorf("hooks/file: invalid level %d, from 0 (panic) to 5 (debug)", c.Level)
+	}
+	if c.FlushLevel < 0 || c.FlushLevel > 5 {
+		return fmt.Errorf("hooks/file: invalid flushlevel %d, from 0 (panic) to 5 (debug)", c.FlushLevel)
+	}
+
+	if _, ok := compressSuffixes[c.Compress]; c.Compress != "" && !ok {
+		return fmt.Errorf("hooks/file: unknown compress %q, use %q or %q", c.Compress, CompressGzip, CompressZlib)
+	}
+	if c.CompressLevel < gzip.HuffmanOnly || c.CompressLevel > gzip.BestCompression {
+		return fmt.Errorf("hooks/file: invalid compresslevel %d, from %d to %d", c.CompressLevel, gzip.HuffmanOnly, gzip.BestCompression)
+	}
+	if _, err := c.nameTemplate(); err != nil {
+		return err
+	}
+	loc, err := c.location()
+	if err != nil {
+		return err
+	}
+	if _, err := c.rotationSchedule(loc); err != nil {
+		return err
+	}
+	if _, err := c.reopenInterval(); err != nil {
+		return err
+	}
+	if _, err := c.flushInterval(); err != nil {
+		return err
+	}
+	switch c.Backpressure {
+	case "", BackpressureBlock, BackpressureDrop:
+	default:
+		return fmt.Errorf("hooks/file: unknown backpressure %q, use %q or %q", c.Backpressure, BackpressureBlock, BackpressureDrop)
+	}
+	if _, ok := reopenSignals[c.ReopenSignal]; c.ReopenSignal != "" && !ok {
+		return fmt.Errorf("hooks/file: unsupported reopensignal %q", c.ReopenSignal)
+	}
+	if c.CopyTruncate && c.Direct {
+		return errors.New("hooks/file: copytruncate and direct can't both be set")
+	}
+	if c.Shared && c.Direct {
+		return errors.New("hooks/file: shared and direct can't both be set")
+	}
+	return nil
+}
+
+// nameTemplate parses RotateName, or the default one of Filename.
+func (c *Config) nameTemplate() (*nameTemplate, error) {
+	if c.RotateName == "" {
+		return parseNameTemplate(defaultRotateName(c.Filename))
+	}
+	return parseNameTemplate(c.RotateName)
+}
+
+// location returns the location of Timezone.
+func (c *Config) location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("hooks/file: invalid timezone %q: %s", c.Timezone, err)
+	}
+	return loc, nil
+}
+
+// rotationSchedule returns the schedule of the rotations in loc, nil if none.
+func (c *Config) rotationSchedule(loc *time.Location) (rotationSchedule, error) {
+	switch {
+	case c.RotateInterval != "" && c.RotateSchedule != "":
+		return nil, errors.New("hooks/file: rotateinterval and rotateschedule can't both be set")
+	case c.RotateSchedule != "":
+		return parseCron(c.RotateSchedule, loc)
+	case c.RotateInterval != "":
+		return parseInterval(c.RotateInterval, loc)
+	case c.Daily:
+		return &intervalSchedule{every: day, loc: loc}, nil
+	}
+	return nil, nil
+}
+
+// reopenInterval returns how often the file is checked, 0 for never.
+func (c *Config) reopenInterval() (time.Duration, error) {
+	if c.ReopenCheck == "" {
+		return defaultReopenCheck, nil
+	}
+	every, err := time.ParseDuration(c.ReopenCheck)
+	if err != nil || every < 0 {
+		return 0, fmt.Errorf("hooks/file: invalid reopencheck %q", c.ReopenCheck)
+	}
+	return every, nil
+}
+
+// flushInterval returns how often the buffer is written at least.
+func (c *Config) flushInterval() (time.Duration, error) {
+	if c.FlushInterval == "" {
+		return defaultFlushInterval, nil
+	}
+	interval, err := time.ParseDuration(c.FlushInterval)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("hooks/file: invalid flushinterval %q", c.FlushInterval)
+	}
+	return interval, nil
+}
@@ -0,0 +1,157 @@
+package file
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig(`{"filename":"app.log","maxsize":1024,"filemode":"0640","dirmode":448}`)
+	assert.NoError(t, err)
+	want := DefaultConfig()
+	want.Filename, want.Maxsize, want.FileMode, want.DirMode = "app.log", 1024, 0640, 0700
+	assert.Equal(t, want, cfg)
+
+	cases := map[string]string{
+		`{"filename":"app.log","daliy":true}`:        `hooks/file: invalid config: json: unknown field "daliy"`,
+		`{"filename":"app.log"} {}`:                  "hooks/file: invalid config: data after the JSON object",
+		`{"filename":"app.log","filemode":"rw-r--"}`: `hooks/file: invalid config: invalid permission "rw-r--", want octal digits such as "0640"`,
+		`{"maxsize":1024}`:                           "hooks/file: filename is required",
+	}
+	for config, msg := range cases {
+		_, err := ParseConfig(config)
+		assert.EqualError(t, err, msg, config)
+	}
+
+	_, err = ParseConfig(`{"filename":"app.log","maxsize":"1MB"}`)
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		set func(*Config)
+		msg string
+	}{
+		{func(c *Config) { c.FileMode = 0440 }, "hooks/file: invalid filemode 0440, the owner must be able to write"},
+		{func(c *Config) { c.DirMode = 01755 }, "hooks/file: invalid dirmode 01755, the owner must be able to write and search"},
+		{func(c *Config) { c.Maxsize = -1 }, "hooks/file: invalid maxsize -1, negative"},
+		{func(c *Config) { c.MaxTotalSize = -1 }, "hooks/file: invalid maxtotalsize -1, negative"},
+		{func(c *Config) { c.Level = 6 }, "hooks/file: invalid level 6, from 0 (panic) to 5 (debug)"},
+		{func(c *Config) { c.Compress = "lz4" }, `hooks/file: unknown compress "lz4", use "gzip" or "zlib"`},
+		{func(c *Config) { c.CompressLevel = 10 }, "hooks/file: invalid compresslevel 10, from -2 to 9"},
+		{func(c *Config) { c.RotateName = "logs/app-%Y.log" }, ""},
+		{func(c *Config) { c.Timezone = "Mars/Olympus_Mons" }, ""},
+		{func(c *Config) { c.RotateInterval = "36h" }, `hooks/file: invalid rotateinterval "36h", longer than a day but not whole days`},
+		{func(c *Config) { c.RotateSchedule = "@yearly" }, `hooks/file: invalid rotateschedule "@yearly", need 5 fields`},
+		{func(c *Config) { c.ReopenCheck = "often" }, `hooks/file: invalid reopencheck "often"`},
+		{func(c *Config) { c.FlushInterval = "0s" }, `hooks/file: invalid flushinterval "0s"`},
+		{func(c *Config) { c.Backpressure = "retry" }, `hooks/file: unknown backpressure "retry", use "block" or "drop"`},
+		{func(c *Config) { c.CopyTruncate, c.Direct = true, true }, "hooks/file: copytruncate and direct can't both be set"},
+		{func(c *Config) { c.Shared, c.Direct = true, true }, "hooks/file: shared and direct can't both be set"},
+	}
+	for _, c := range cases {
+		cfg := DefaultConfig()
+		cfg.Filename = "app.log"
+		c.set(&cfg)
+		err := cfg.Validate()
+		if c.msg == "" {
+			assert.Error(t, err)
+		} else {
+			assert.EqualError(t, err, c.msg)
+		}
+	}
+}
+
+func TestOpenWriterPermissions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := DefaultConfig()
+	cfg.Filename = filepath.Join(dir, "a", "b", "app.log")
+	cfg.FileMode, cfg.DirMode = 0600, 0700
+	w, err := OpenWriter(cfg)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	info, err := os.Stat(cfg.Filename)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	for _, d := range []string{filepath.Join(dir, "a"), filepath.Join(dir, "a", "b")} {
+		info, err = os.Stat(d)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0700), info.Mode().Perm(), d)
+	}
+
+	// a file is in the way of the directory
+	cfg.Filename = filepath.Join(cfg.Filename, "app.log")
+	_, err = OpenWriter(cfg)
+	assert.Error(t, err)
+}
+
+func TestOpenWriterZeroValues(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := OpenWriter(Config{Filename: filepath.Join(dir, "logs", "app.log")})
+	assert.NoError(t, err)
+	assert.Equal(t, Perm(0660), w.FileMode)
+	assert.Equal(t, Perm(0755), w.DirMode)
+	assert.Equal(t, 4, w.Level)
+	assert.Equal(t, gzip.DefaultCompression, w.CompressLevel)
+	assert.False(t, w.Daily)
+	assert.NoError(t, w.WriteMsg("info", 4))
+	assert.NoError(t, w.WriteMsg("debug", 5))
+	assert.NoError(t, w.Close())
+	assert.Equal(t, "info\n", readFile(t, w.Filename))
+
+	hook, err := NewHook(Config{Filename: filepath.Join(dir, "hook.log"), PrintFormat: "%M", AsyncBuffer: true})
+	assert.NoError(t, err)
+	w = hook.W.(*FileLogWriter)
+	assert.Equal(t, 2, w.FlushLevel)
+	assert.Equal(t, 8*1024, w.BufferSize)
+	assert.NoError(t, hook.Close())
+
+	// the zero values of a configuration started from DefaultConfig are kept
+	cfg, err := ParseConfig(fmt.Sprintf(`{"filename":%q,"level":0,"flushlevel":0,"compresslevel":0}`, filepath.Join(dir, "json.log")))
+	assert.NoError(t, err)
+	w, err = OpenWriter(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, w.Level)
+	assert.Equal(t, 0, w.FlushLevel)
+	assert.Equal(t, gzip.NoCompression, w.CompressLevel)
+	assert.True(t, w.Daily)
+	assert.NoError(t, w.Close())
+}
+
+func TestNewHook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := DefaultConfig()
+	cfg.Filename = filepath.Join(dir, "app.log")
+	cfg.PrintFormat = "%L %M"
+	hook, err := NewHook(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "%L %M", hook.PrintFormat)
+	assert.NoError(t, hook.Close())
+
+	hook, err = NewHookJSON(fmt.Sprintf(`{"filename":%q,"printformat":"%%M"}`, cfg.Filename), "[%L] %M")
+	assert.NoError(t, err)
+	assert.Equal(t, "[%L] %M", hook.PrintFormat)
+	assert.NoError(t, hook.Close())
+
+	cfg.Maxdays = -7
+	_, err = NewHook(cfg)
+	assert.EqualError(t, err, "hooks/file: invalid maxdays -7, negative")
+	_, err = NewHookJSON(`{"filename":"app.log","daliy":true}`, "%M")
+	assert.EqualError(t, err, `hooks/file: invalid config: json: unknown field "daliy"`)
+}
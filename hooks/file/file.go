@@ -15,8 +15,6 @@
 package file
 
 import (
-	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -25,7 +23,6 @@ import (
 	"strings"
 	"sync"
 	"time"
-	"os/exec"
 )
 
 type LoggerInterface interface {
@@ -40,34 +37,48 @@ type LoggerInterface interface {
 type FileLogWriter struct {
 	*log.Logger
 	mw *MuxWriter
-	// The opened file
-	Filename string `json:"filename"`
 
-	Maxlines int `json:"maxlines"`
+	// The configuration, set by Init or OpenWriter.
+	Config
+
 	maxlines_curlines int
+	maxsize_cursize   int
+
+	loc          *time.Location
+	schedule     rotationSchedule
+	nextRotation time.Time // zero without schedule
+	opened       time.Time // when the current file was opened
+	rotateLock   sync.Mutex
+	stop         chan struct{}
+
+	nameTmpl *nameTemplate
+	current  string // the file written to
+	period   string // the name of the current file without counter
 
-	// Rotate at size
-	Maxsize  int `json:"maxsize"`
-	maxsize_cursize int
+	reopenEvery time.Duration
+	nextCheck   time.Time
+	checkedSize int64 // the size of the file when last checked
+	signalStop  chan struct{}
 
-	// Rotate daily
-	Daily    bool  `json:"daily"`
-	Maxdays  int64 `json:"maxdays"`
-	daily_opendate int
+	lockFd *os.File
 
-	Rotate bool `json:"rotate"`
+	background     sync.WaitGroup // compressing and deleting rotated files
+	backgroundLock sync.Mutex     // one of them at a time, see runInBackground
 
 	startLock sync.Mutex
-	Level int `json:"level"`
-
-	// 18/09/2017新增:
-	//   日志缓存模块,批量刷新到磁盘;
-	//   Add by 164776775@qq.com WeChat:164776775 谷子慧
-	AsyncBuffer     bool `json:"asyncbuffer"`// 默认: false.
-	BufferSize       int `json:"buffersize"` // 缓冲区大小,默认: 8KB
-	buffer     [2][]byte  // 日志在写入文件之前的缓冲区;
-	bufchan chan *[]byte  // 当缓冲区写满时,将缓冲地址发往管道,供刷新协程刷入文件;
-	bufptr       *[]byte  // 2个缓冲区,指针来回切换,指向空缓冲区;(日志信息通过该指针写入缓冲);
+
+	writing sync.RWMutex // held by the writes without AsyncBuffer and Reopen, and by Close
+
+	buf          []byte             // 正在写入的缓冲区, under startLock
+	room         *sync.Cond         // signaled when there is room in full
+	blocked      bool               // a writer waits for room in full
+	full         chan []byte        // 已写满的缓冲区, owned by flushLoop
+	free         chan []byte        // 已刷新的缓冲区, for reuse
+	flushReq     chan chan struct{} // flushLoop closes the channel once flushed
+	flushDone    chan struct{}      // closed when flushLoop returns
+	closed       bool
+	dropped      uint64 // atomic
+	droppedSince uint64 // not reported in the file yet, under startLock
 }
 
 // an *os.File writer with locker.
@@ -93,42 +104,82 @@ func (l *MuxWriter) SetFd(fd *os.File) {
 
 // create a FileLogWriter returning as LoggerInterface.
 func NewFileWriter() LoggerInterface {
-	w := &FileLogWriter{
-		Filename: "",
-		Maxlines: 1000000,
-		Maxsize:  1 << 28, //256 MB
-		Daily:    true,
-		Maxdays:  7,
-		Rotate:   true,
-		Level:    4, // info level.
-		AsyncBuffer: false,
-		BufferSize:  8 * 1024,
-	}
+	return newFileLogWriter()
+}
+
+// create a FileLogWriter with the default settings, to be initialized.
+func newFileLogWriter() *FileLogWriter {
+	w := &FileLogWriter{Config: DefaultConfig()}
 	// use MuxWriter instead direct use os.File for lock write when rotate
 	w.mw = new(MuxWriter)
 
 	return w
 }
 
+// OpenWriter returns a FileLogWriter writing to the file of cfg, see Config
+// for the zero values. The directory of the file is created if need be.
+func OpenWriter(cfg Config) (*FileLogWriter, error) {
+	w := newFileLogWriter()
+	w.Config = cfg.withDefaults()
+	if err := w.init(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Init sets the configuration in JSON over the current one, see ParseConfig,
+// and opens the file.
 func (w *FileLogWriter) Init(json_config string) error {
-	err := json.Unmarshal([]byte(json_config), w)
+	if err := decodeConfig(json_config, &w.Config); err != nil {
+		return err
+	}
+	return w.init()
+}
+
+func (w *FileLogWriter) init() error {
+	err := w.Validate()
 	if err != nil {
 		return err
 	}
-	if len(w.Filename) == 0 {
-		return errors.New("json_config must have filename")
+	if w.RotateName == "" {
+		w.RotateName = defaultRotateName(w.Filename)
 	}
-	// filepath.Split() return dir & filename,
-	// if w.Filename doesn't contain path, then dir is null-string("").
-	dir, _ := filepath.Split(w.Filename)
-	if len(dir) != 0 {
-		if err = exec.Command("mkdir", "-p", dir).Run(); err != nil {
-			return fmt.Errorf("hooks/file: `mkdir -p %s` error:%v", dir, err)
-		}
+	if w.nameTmpl, err = w.nameTemplate(); err != nil {
+		return err
+	}
+	if err = w.initSchedule(); err != nil {
+		return err
+	}
+	if err = w.makeDir(w.Filename); err != nil {
+		return err
+	}
+	if err = w.initShared(); err != nil {
+		return err
+	}
+	w.current = w.Filename
+	if w.Direct {
+		w.current = w.nextName(time.Now(), true)
 	}
 	if err = w.startLogger(); err != nil {
 		return err
 	}
+	unlock, err := w.lockShared()
+	if err != nil {
+		return err
+	}
+	err = w.recoverSegments()
+	unlock()
+	if err != nil {
+		return err
+	}
+	if err = w.initReopen(); err != nil {
+		return err
+	}
+	if w.Rotate && w.schedule != nil {
+		// rotate on time even without messages
+		w.stop = make(chan struct{})
+		go w.rotateOnSchedule(w.stop)
+	}
 	if !w.AsyncBuffer {
 		// 同步版本:
 		// 保留源码,不改动,默认:这里设置了日期和时间在日志中的展示,所以,配置PrintFormat时,可不用重复添加`%d %t`参考:record.go);
@@ -136,80 +187,25 @@ func (w *FileLogWriter) Init(json_config string) error {
 		w.Logger = log.New(w.mw, "", 0)//log.Ldate|log.Ltime)
 	} else {
 		// 异步缓存版本: 就不用log模块了,直接从内存刷入磁盘;
-		if w.BufferSize < 8*1024 {
-			w.BufferSize = 8*1024
+		if err = w.initAsync(); err != nil {
+			return err
 		}
-		// 经测试发现:
-		// 当容量大小一定时,通过append执行拷贝数据,比copy几乎高一倍;
-		// 另一好处是: append可以使用len()函数,但copy的len值是一定的,无法更改;
-		w.buffer[0] = make([]byte, 0, w.BufferSize)
-		w.buffer[1] = make([]byte, 0, w.BufferSize)
-		w.bufptr  = &w.buffer[0]
-		w.bufchan = make(chan *[]byte)
-
-		// 启动缓冲模块
-		go w.startBuffer()
 	}
 
 	return nil
 }
 
-func (w *FileLogWriter) startBuffer() {
-	for {
-		select {
-		// 1.频繁整块刷新日志入库;
-		case ptr := <-w.bufchan: // channel中保存的是已写满的缓冲区的地址;
-			{
-				if err := w.writeLogger(ptr); err != nil {
-					fmt.Printf("Fatal: (new buffer) w.writeLogger(ptr:%p) Err:%s\n", ptr, err)
-                    // TODO: retry for 3 times?
-				}
-			}
-		// 2.当超时5秒后,如果仍没有新的日志写满buffer,就将buffer中的日志刷新到磁盘;
-		//   如果在这5秒间隔内,程序挂掉了,那没办法,缓冲区中的日志将会丢失;
-		case <-time.After(time.Second * 5):
-			{
-				if len(*w.bufptr) > 0 {
-					ptr := w.bufptr
-
-					w.startLock.Lock()
-					if w.bufptr == &w.buffer[0] {
-						w.bufptr = &w.buffer[1]
-					} else {
-						w.bufptr = &w.buffer[0]
-					}
-					w.startLock.Unlock()
-
-					if err := w.writeLogger(ptr); err != nil {
-						fmt.Printf("Fatal: after(5s) w.writeLogger(ptr:%p) Err:%s\n", ptr, err)
-						// TODO: retry for 3 times?
-					}
-				}
-			}
+// makeDir creates the directory of name with DirMode, and its parents, if
+// it doesn't exist.
+func (w *FileLogWriter) makeDir(name string) error {
+	// filepath.Split() return dir & filename,
+	// if name doesn't contain path, then dir is null-string("").
+	dir, _ := filepath.Split(name)
+	if len(dir) != 0 {
+		if err := os.MkdirAll(dir, os.FileMode(w.DirMode)); err != nil {
+			return fmt.Errorf("hooks/file: creating the directory of %s: %s", name, err)
 		}
 	}
-}
-
-// 刷新到日志文件;
-func (w *FileLogWriter) writeLogger(ptr *[]byte) error {
-	//start := time.Now()
-
-	bfrlen := len(*ptr)
-	if _, err := w.mw.fd.Write(*ptr); err != nil {
-		return fmt.Errorf("fd.Write(bufptr:%p buflen:%d) Err:%s", *ptr, bfrlen, err)
-	}
-	//fmt.Printf("recv:%p bfr:%p len(buffer):%d Cost:%v Now:%v\n", ptr, *ptr, bfrlen, time.Now().Sub(start), time.Now().UnixNano())
-
-	// 先将所有的日志数据刷新到文本,然后再判断并打开新的日志文件;
-	//start = time.Now()
-	w.docheck(bfrlen)
-	//fmt.Printf("END: %p docheck: bfr:%p len(buffer):%d Cost:%v Now:%v\n\n", ptr, *ptr, bfrlen, time.Now().Sub(start), time.Now().UnixNano())
-
-	//start = time.Now()
-	// 清空缓存空间;
-	*ptr = (*ptr)[0:0] // 情况SLICE就这么简单;
-	//fmt.Printf("bfr:%p len(buffer):%d Cost:%v\n", *ptr, bfrlen, time.Now().Sub(start))
-
 	return nil
 }
 
@@ -224,25 +220,31 @@ func (w *FileLogWriter) startLogger() error {
 	if err != nil {
 		return err
 	}
-	return nil
+	return w.updateSymlink()
 }
 
-func (w *FileLogWriter) docheck(size int) {
+func (w *FileLogWriter) docheck(size, lines int) {
 	if !w.AsyncBuffer {
         w.startLock.Lock()
         defer w.startLock.Unlock()
 	}
+	w.rotateLock.Lock()
+	defer w.rotateLock.Unlock()
+
+	now := time.Now()
+	w.checkFile(now)
 	if w.Rotate && ((w.Maxlines > 0 && w.maxlines_curlines >= w.Maxlines) ||
 		(w.Maxsize > 0 && w.maxsize_cursize >= w.Maxsize) ||
-		(w.Daily && time.Now().Day() != w.daily_opendate)) {
+		(!w.nextRotation.IsZero() && !now.Before(w.nextRotation)) ||
+		(w.Direct && w.periodOf(now) != w.period)) {
 		if err := w.DoRotate(); err != nil {
 			fmt.Fprintf(os.Stderr, "FileLogWriter(%q): %s\n", w.Filename, err)
+			w.skipRotation(now)
 			return
 		}
+		w.skipRotation(now)
 	}
-    if !w.AsyncBuffer {
-		w.maxlines_curlines++
-	}
+	w.maxlines_curlines += lines
 	w.maxsize_cursize += size
 }
 
@@ -252,30 +254,33 @@ func (w *FileLogWriter) WriteMsg(msg string, level int) error {
 		return nil
 	}
 	if !w.AsyncBuffer {
-		w.docheck(len(msg))
-		w.Logger.Print(msg)
-	} else {
-        w.startLock.Lock()
-		if len(*w.bufptr) + len([]byte(msg)) > w.BufferSize {
-			// 1.先将待刷新缓冲区的首地址发送给管道;
-			w.bufchan <- w.bufptr
-			//fmt.Printf("\n\nSTART: Send %p to channel. Now:%v\n", w.bufptr, time.Now().UnixNano())
-
-			// 2.切换到另外一个缓冲区;
-			if w.bufptr == &w.buffer[0] {
-				w.bufptr = &w.buffer[1]
-			} else {
-				w.bufptr = &w.buffer[0]
-			}
+		if len(msg) == 0 || msg[len(msg)-1] != '\n' {
+			msg += "\n"
 		}
-		*w.bufptr = append(*w.bufptr, msg...)
-		w.startLock.Unlock()
+		_, err := w.writeSync([]byte(msg), 1)
+		return err
 	}
-	return nil
+	return w.writeAsync(msg, true, level <= w.FlushLevel)
+}
+
+// writeSync writes p of the given number of lines to the file, rotating it
+// before if need be. Close waits for it, so that the file isn't closed, or
+// opened again by a rotation, while written.
+func (w *FileLogWriter) writeSync(p []byte, lines int) (int, error) {
+	w.writing.RLock()
+	defer w.writing.RUnlock()
+	if w.closed {
+		return 0, ErrClosed
+	}
+	if w.Shared {
+		return w.writeShared(p)
+	}
+	w.docheck(len(p), lines)
+	return w.mw.Write(p)
 }
 
 func (w *FileLogWriter) createLogFile() (*os.File, error) {
-	fd, err := os.OpenFile(w.Filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0660)
+	fd, err := os.OpenFile(w.current, os.O_WRONLY|os.O_APPEND|os.O_CREATE, os.FileMode(w.FileMode))
 	return fd, err
 }
 
@@ -286,9 +291,14 @@ func (w *FileLogWriter) initFd() error {
 		return fmt.Errorf("hooks/file: fd.Stat() err: %s\n", err)
 	}
 	w.maxsize_cursize = int(finfo.Size())
-	w.daily_opendate = time.Now().Day()
-	if finfo.Size() > 0 {
-		content, err := ioutil.ReadFile(w.Filename)
+	w.checkedSize = finfo.Size()
+	w.opened = time.Now()
+	w.period = w.periodOf(w.opened)
+	if w.schedule != nil {
+		w.nextRotation = w.schedule.next(w.opened)
+	}
+	if finfo.Size() > 0 && !w.Shared {
+		content, err := ioutil.ReadFile(w.current)
 		if err != nil {
 			return err
 		}
@@ -300,75 +310,114 @@ func (w *FileLogWriter) initFd() error {
 }
 
 // DoRotate means it need to write file in new file.
-// new file name like xx.log.2013-01-01.2
+// The file is renamed as named by RotateName for the time it was opened,
+// like xx.log.2013-01-01.002, or copied there then truncated with
+// CopyTruncate, or with Direct the next file named by RotateName is opened
+// instead. The previous file is compressed afterwards
+// when Compress is set.
 func (w *FileLogWriter) DoRotate() error {
-	_, err := os.Lstat(w.Filename)
+	_, err := os.Lstat(w.current)
 	if err == nil { // file exists
-		// Find the next available number
-		num := 1
-		fname := ""
-		for ; err == nil && num <= 999; num++ {
-			fname = w.Filename + fmt.Sprintf(".%s.%03d", time.Now().Format("2006-01-02"), num)
-			_, err = os.Lstat(fname)
-		}
-		// return error if the last file checked still existed
-		if err == nil {
-			return fmt.Errorf("Rotate: Cannot find free log number to rename %s\n", w.Filename)
+		// Find the next available name, compressed files included
+		var fname string
+		if w.Direct {
+			fname = w.nextName(time.Now(), false)
+		} else {
+			fname = w.nextName(w.opened, false)
 		}
 
 		// block Logger's io.Writer
 		w.mw.Lock()
 		defer w.mw.Unlock()
 
-		fd := w.mw.fd
-		fd.Close()
-
-		// close fd before rename
-		// Rename the file to its newfound home
-		err = os.Rename(w.Filename, fname)
-		if err != nil {
-			return fmt.Errorf("Rotate: %s\n", err)
+		previous := w.current
+		if w.CopyTruncate {
+			// the file stays open
+			if err = w.copyTruncate(fname); err != nil {
+				return fmt.Errorf("Rotate: %s\n", err)
+			}
+			previous = fname
+		} else if w.Direct {
+			w.mw.fd.Close()
+			w.current = fname
+		} else {
+			w.mw.fd.Close()
+			// close fd before rename
+			// Rename the file to its newfound home
+			err = os.Rename(w.Filename, fname)
+			if err != nil {
+				return fmt.Errorf("Rotate: %s\n", err)
+			}
+			previous = fname
 		}
 
 		// re-start logger
-		err = w.startLogger()
-		if err != nil {
-			return fmt.Errorf("Rotate StartLogger: %s\n", err)
+		if !w.CopyTruncate {
+			err = w.startLogger()
+			if err != nil {
+				return fmt.Errorf("Rotate StartLogger: %s\n", err)
+			}
 		}
 
-		go w.deleteOldLog()
+		if w.Compress != "" {
+			w.compressInBackground(previous)
+		} else {
+			w.runInBackground(w.deleteOldLog)
+		}
 	}
 
 	return nil
 }
 
-func (w *FileLogWriter) deleteOldLog() {
-	dir := filepath.Dir(w.Filename)
-	filepath.Walk(dir, func(path string, info os.FileInfo, err error) (returnErr error) {
-		defer func() {
-			if r := recover(); r != nil {
-				returnErr = fmt.Errorf("hooks/file: Unable to delete old log '%s', error: %+v", path, r)
-				fmt.Println(returnErr)
-			}
-		}()
-
-		if !info.IsDir() && info.ModTime().Unix() < (time.Now().Unix()-60*60*24*w.Maxdays) {
-			if strings.HasPrefix(filepath.Base(path), filepath.Base(w.Filename)) {
-				os.Remove(path)
-			}
-		}
-		return
-	})
+// destroy file logger, see Close.
+func (w *FileLogWriter) Destroy() {
+	w.Close()
 }
 
-// destroy file logger, close file writer.
-func (w *FileLogWriter) Destroy() {
-	w.mw.fd.Close()
+// Close writes everything buffered, stops the rotations and closes the file,
+// waiting for the rotated files being compressed and deleted. Messages written
+// afterwards return ErrClosed.
+func (w *FileLogWriter) Close() error {
+	// wait for the writes in progress
+	w.writing.Lock()
+	if w.AsyncBuffer {
+		w.closeAsync()
+	} else {
+		w.startLock.Lock()
+		w.closed = true
+		w.startLock.Unlock()
+	}
+	w.writing.Unlock()
+
+	w.rotateLock.Lock()
+	if w.stop != nil {
+		close(w.stop)
+		w.stop = nil
+	}
+	if w.signalStop != nil {
+		close(w.signalStop)
+		w.signalStop = nil
+	}
+	if w.lockFd != nil {
+		w.lockFd.Close()
+		w.lockFd = nil
+	}
+	w.rotateLock.Unlock()
+
+	w.mw.Lock()
+	err := w.mw.fd.Close()
+	w.mw.Unlock()
+	w.background.Wait()
+	return err
 }
 
 // flush file logger.
-// there are no buffering messages in file logger in memory.
-// flush file means sync file from disk.
+// the buffered messages are written first, then the file is synced to disk.
 func (w *FileLogWriter) Flush() {
+	if w.AsyncBuffer {
+		w.flushBuffers()
+	}
+	w.mw.Lock()
 	w.mw.fd.Sync()
+	w.mw.Unlock()
 }
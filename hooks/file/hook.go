@@ -1,20 +1,39 @@
 package file
 
 import (
-    "fmt"
+    "io"
+    "time"
+
 	"github.com/logrus"
 )
 
+// NewHook returns a hook writing the entries formatted with cfg.PrintFormat
+// to the file of cfg, opened with OpenWriter.
 // level: see, github.com/logrus/logrus.go const's XxxLevel
-func NewHook(jsonConfig, printFormat string) *FileHook {
-
-    w := NewFileWriter()
+func NewHook(cfg Config) (*FileHook, error) {
+	w, err := OpenWriter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterHook(w, cfg.PrintFormat), nil
+}
 
-    if err := w.Init(jsonConfig); err != nil {
-        fmt.Printf("hooks: FileWriter.Init(%s) error:%v\n", jsonConfig, err)
-        panic(err)
-    }
+// NewHookJSON is NewHook with the configuration in JSON, see ParseConfig.
+// printFormat, when set, replaces "printformat".
+func NewHookJSON(jsonConfig, printFormat string) (*FileHook, error) {
+	cfg, err := ParseConfig(jsonConfig)
+	if err != nil {
+		return nil, err
+	}
+	if printFormat != "" {
+		cfg.PrintFormat = printFormat
+	}
+	return NewHook(cfg)
+}
 
+// NewWriterHook returns a hook writing the entries formatted with printFormat
+// to w, which may be the Out of a logger as well.
+func NewWriterHook(w *FileLogWriter, printFormat string) *FileHook {
 	return &FileHook{
 		W: w,
 		PrintFormat: printFormat,
@@ -24,18 +43,37 @@ func NewHook(jsonConfig, printFormat string) *FileHook {
 type FileHook struct {
 	PrintFormat string
 	W LoggerInterface
+
+	// Sanitize control characters in the message, escaping them by default,
+	// so that it can't forge lines of the file.
+	Sanitize logrus.SanitizePolicy
+
+	// Location of the timestamps, defaults to the location of the entry time.
+	Location *time.Location
 }
 
 func (hook *FileHook) Fire(entry *logrus.Entry) (err error) {
 
     // 使用 logrus.record.go 中的相关API,
     // 跟log_formatter.go<A>没啥关系,A只针对打印到终端有用;
-    record  := logrus.Prepare(entry.Level, entry.Message, entry.Logger.PkgPath)
-    message := logrus.NewPrintFormat(hook.PrintFormat).Format(record)
+    record  := logrus.PrepareEntry(entry)
+    printFormat := logrus.NewPrintFormat(hook.PrintFormat)
+    printFormat.Sanitize = hook.Sanitize
+    printFormat.Location = hook.Location
+    message := printFormat.Format(record)
 
     return hook.W.WriteMsg(message, int(entry.Level))
 }
 
+// Close closes the writer of the hook, writing everything buffered.
+func (hook *FileHook) Close() error {
+	if c, ok := hook.W.(io.Closer); ok {
+		return c.Close()
+	}
+	hook.W.Destroy()
+	return nil
+}
+
 func (hook *FileHook) Levels() []logrus.Level {
 	return []logrus.Level{
 		logrus.PanicLevel,
@@ -0,0 +1,18 @@
+// +build windows
+
+package file
+
+import (
+	"errors"
+	"os"
+)
+
+var errLockUnsupported = errors.New("hooks/file: shared is not supported on Windows")
+
+func lockFile(f *os.File) error {
+	return errLockUnsupported
+}
+
+func unlockFile(f *os.File) error {
+	return errLockUnsupported
+}
@@ -0,0 +1,299 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nameTemplate is a parsed FileLogWriter.RotateName.
+type nameTemplate struct {
+	parts   []namePart
+	counter bool // whether the template has %N
+	regexp  *regexp.Regexp
+	groups  []byte // the verb of every group of regexp
+}
+
+// namePart is a literal, or a verb of a strftime directive when verb isn't 0.
+type namePart struct {
+	literal string
+	verb    byte
+}
+
+// nameVerbs are the regexps of the supported strftime verbs, N being the
+// counter.
+var nameVerbs = map[byte]string{
+	'Y': `\d{4}`,
+	'y': `\d{2}`,
+	'm': `\d{2}`,
+	'b': `[A-Z][a-z]{2}`,
+	'd': `\d{2}`,
+	'j': `\d{3}`,
+	'H': `\d{2}`,
+	'M': `\d{2}`,
+	'S': `\d{2}`,
+	'N': `\d+`,
+}
+
+// nameLayout are the elements of the Go time layouts supported, and the
+// strftime verbs they stand for, longest first.
+var nameLayout = []struct {
+	element string
+	verb    byte
+}{
+	{"2006", 'Y'}, {"002", 'j'}, {"Jan", 'b'}, {"01", 'm'}, {"02", 'd'},
+	{"15", 'H'}, {"04", 'M'}, {"05", 'S'}, {"06", 'y'},
+}
+
+// significance orders the verbs for sorting, most significant first.
+const significance = "YyjmbdHMS"
+
+// parseNameTemplate parses a strftime-like template such as
+// "app-%Y%m%d-%H.log", or a Go time layout such as "app.2006-01-02.log". Both
+// can hold the counter %N. Templates with another % than %N are strftime
+// ones.
+func parseNameTemplate(s string) (*nameTemplate, error) {
+	if s == "" || strings.ContainsRune(s, filepath.Separator) || strings.ContainsRune(s, '/') {
+		return nil, fmt.Errorf("hooks/file: invalid rotatename %q, must be a file name", s)
+	}
+
+	t := &nameTemplate{}
+	literal := ""
+	add := func(verb byte) {
+		if literal != "" {
+			t.parts = append(t.parts, namePart{literal: literal})
+			literal = ""
+		}
+		t.parts = append(t.parts, namePart{verb: verb})
+		t.counter = t.counter || verb == 'N'
+	}
+
+	if strings.Contains(strings.Replace(s, "%N", "", -1), "%") {
+		for i := 0; i < len(s); i++ {
+			if s[i] != '%' {
+				literal += s[i : i+1]
+				continue
+			}
+			if i+1 == len(s) {
+				return nil, fmt.Errorf("hooks/file: invalid rotatename %q, ends with %%", s)
+			}
+			i++
+			switch verb := s[i]; {
+			case verb == '%':
+				literal += "%"
+			case nameVerbs[verb] != "":
+				add(verb)
+			default:
+				return nil, fmt.Errorf("hooks/file: invalid rotatename %q, unknown %%%c", s, verb)
+			}
+		}
+	} else {
+	next:
+		for i := 0; i < len(s); i++ {
+			if strings.HasPrefix(s[i:], "%N") {
+				add('N')
+				i++
+				continue
+			}
+			for _, l := range nameLayout {
+				if strings.HasPrefix(s[i:], l.element) {
+					add(l.verb)
+					i += len(l.element) - 1
+					continue next
+				}
+			}
+			literal += s[i : i+1]
+		}
+	}
+	if literal != "" {
+		t.parts = append(t.parts, namePart{literal: literal})
+	}
+
+	expr := "^"
+	for _, p := range t.parts {
+		if p.verb == 0 {
+			expr += regexp.QuoteMeta(p.literal)
+			continue
+		}
+		expr += "(" + nameVerbs[p.verb] + ")"
+		t.groups = append(t.groups, p.verb)
+	}
+	if !t.counter {
+		// the counter is appended when the name is taken
+		expr += `(?:\.(\d+))?`
+		t.groups = append(t.groups, 'N')
+	}
+	expr += `((?:\.gz|\.zz)?)((?:` + regexp.QuoteMeta(tmpSuffix) + `)?)$`
+	t.regexp = regexp.MustCompile(expr)
+	return t, nil
+}
+
+// first is the first counter of the names.
+func (t *nameTemplate) first() int {
+	if t.counter {
+		return 1
+	}
+	return 0
+}
+
+// format returns the name for the time tm and the counter n.
+func (t *nameTemplate) format(tm time.Time, n int) string {
+	name := ""
+	for _, p := range t.parts {
+		switch p.verb {
+		case 0:
+			name += p.literal
+		case 'Y':
+			name += fmt.Sprintf("%04d", tm.Year())
+		case 'y':
+			name += fmt.Sprintf("%02d", tm.Year()%100)
+		case 'm':
+			name += fmt.Sprintf("%02d", int(tm.Month()))
+		case 'b':
+			name += tm.Month().String()[:3]
+		case 'd':
+			name += fmt.Sprintf("%02d", tm.Day())
+		case 'j':
+			name += fmt.Sprintf("%03d", tm.YearDay())
+		case 'H':
+			name += fmt.Sprintf("%02d", tm.Hour())
+		case 'M':
+			name += fmt.Sprintf("%02d", tm.Minute())
+		case 'S':
+			name += fmt.Sprintf("%02d", tm.Second())
+		case 'N':
+			name += fmt.Sprintf("%03d", n)
+		}
+	}
+	if !t.counter && n > 0 {
+		name += "." + strconv.Itoa(n)
+	}
+	return name
+}
+
+// period returns the name of the time tm without counter, which changes when
+// the time of the name does.
+func (t *nameTemplate) period(tm time.Time) string {
+	return t.format(tm, 0)
+}
+
+// segmentName is a name matched by a nameTemplate.
+type segmentName struct {
+	key    string // the time of the name, most significant first
+	num    int
+	suffix string // of the compression
+	tmp    bool
+}
+
+// parse parses the name of a file written with the template, compressed or
+// not.
+func (t *nameTemplate) parse(name string) (segmentName, bool) {
+	m := t.regexp.FindStringSubmatch(name)
+	if m == nil {
+		return segmentName{}, false
+	}
+
+	values := make(map[byte]string, len(t.groups))
+	for i, verb := range t.groups {
+		values[verb] = m[i+1]
+	}
+	s := segmentName{suffix: m[len(m)-2], tmp: m[len(m)-1] != ""}
+	for i := 0; i < len(significance); i++ {
+		v, ok := values[significance[i]]
+		if !ok {
+			continue
+		}
+		if significance[i] == 'b' {
+			for month := time.January; month <= time.December; month++ {
+				if month.String()[:3] == v {
+					v = fmt.Sprintf("%02d", int(month))
+				}
+			}
+		}
+		s.key += v
+	}
+	s.num, _ = strconv.Atoi(values['N'])
+	return s, true
+}
+
+// defaultRotateName names the rotated files like xx.log.2013-01-01.001.
+func defaultRotateName(filename string) string {
+	return strings.Replace(filepath.Base(filename), "%", "%%", -1) + ".%Y-%m-%d.%N"
+}
+
+// names returns the template of the names of the rotated files, or of all the
+// files with Direct.
+func (w *FileLogWriter) names() *nameTemplate {
+	if w.nameTmpl != nil {
+		return w.nameTmpl
+	}
+	t, err := parseNameTemplate(w.RotateName)
+	if err != nil {
+		// checked by Init, unless used without it
+		t, _ = parseNameTemplate(defaultRotateName(w.Filename))
+	}
+	w.nameTmpl = t
+	return t
+}
+
+// localTime returns t in the Timezone of w.
+func (w *FileLogWriter) localTime(t time.Time) time.Time {
+	if w.loc == nil {
+		return t
+	}
+	return t.In(w.loc)
+}
+
+// periodOf returns the name of the files for the time t, without counter.
+func (w *FileLogWriter) periodOf(t time.Time) string {
+	return w.names().period(w.localTime(t))
+}
+
+// nextName returns the path of a file free for the time tm. With reuse, the
+// last file of that time is returned instead, unless it was compressed.
+func (w *FileLogWriter) nextName(tm time.Time, reuse bool) string {
+	dir := filepath.Dir(w.Filename)
+	t := w.names()
+	last := ""
+	for n := t.first(); ; n++ {
+		name := filepath.Join(dir, t.format(w.localTime(tm), n))
+		if !segmentExists(name) && name != w.current {
+			if reuse && last != "" {
+				if info, err := os.Lstat(last); err == nil && info.Mode().IsRegular() {
+					return last
+				}
+			}
+			return name
+		}
+		last = name
+	}
+}
+
+// updateSymlink points Symlink to the current file, replacing it atomically.
+func (w *FileLogWriter) updateSymlink() error {
+	if w.Symlink == "" {
+		return nil
+	}
+	if info, err := os.Lstat(w.Symlink); err == nil && info.Mode()&os.ModeSymlink == 0 {
+		return fmt.Errorf("hooks/file: %s exists and is not a symlink", w.Symlink)
+	}
+
+	target := w.current
+	if filepath.Dir(target) == filepath.Dir(w.Symlink) {
+		target = filepath.Base(target)
+	}
+	tmp := w.Symlink + ".new"
+	os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return fmt.Errorf("hooks/file: %s", err)
+	}
+	if err := os.Rename(tmp, w.Symlink); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("hooks/file: %s", err)
+	}
+	return nil
+}
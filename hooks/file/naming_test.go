@@ -0,0 +1,119 @@
+package file
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNameTemplate(t *testing.T) {
+	tm := time.Date(2017, 7, 5, 9, 56, 30, 0, time.UTC)
+	cases := []struct {
+		template, name, name2 string
+	}{
+		{"app-%Y%m%d-%H.log", "app-20170705-09.log", "app-20170705-09.log.2"},
+		{"app.log.%Y-%m-%d.%N", "app.log.2017-07-05.001", "app.log.2017-07-05.002"},
+		{"app.2006-01-02T15.%N.log", "app.2017-07-05T09.001.log", "app.2017-07-05T09.002.log"},
+		{"app-%y%j-%b-100%%.log", "app-17186-Jul-100%.log", "app-17186-Jul-100%.log.2"},
+	}
+	for _, c := range cases {
+		tmpl, err := parseNameTemplate(c.template)
+		assert.NoError(t, err, c.template)
+		assert.Equal(t, c.name, tmpl.format(tm, tmpl.first()), c.template)
+		assert.Equal(t, c.name2, tmpl.format(tm, 2), c.template)
+
+		for _, name := range []string{c.name, c.name2, c.name2 + ".gz", c.name + ".zz.tmp"} {
+			_, ok := tmpl.parse(name)
+			assert.True(t, ok, name)
+		}
+		_, ok := tmpl.parse(c.name + ".old")
+		assert.False(t, ok)
+	}
+
+	for _, template := range []string{"", "logs/app-%Y.log", "app-%Q.log", "app-%"} {
+		_, err := parseNameTemplate(template)
+		assert.Error(t, err, template)
+	}
+}
+
+func TestNameTemplateOrder(t *testing.T) {
+	tmpl, err := parseNameTemplate("app-%d-%b-%Y.log")
+	assert.NoError(t, err)
+
+	older, _ := tmpl.parse("app-30-Jun-2017.log.12")
+	newer, _ := tmpl.parse("app-01-Jul-2017.log")
+	assert.True(t, older.key < newer.key)
+	assert.Equal(t, 12, older.num)
+}
+
+func TestRotateNoDailyLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	today := time.Now().Format("2006-01-02")
+	for n := 1; n <= 999; n++ {
+		name := filepath.Join(dir, fmt.Sprintf("app.log.%s.%03d", today, n))
+		assert.NoError(t, ioutil.WriteFile(name, nil, 0660))
+	}
+
+	w := newTestWriter(t, dir, `,"maxdays":0`)
+	assert.NoError(t, w.WriteMsg("first", 4))
+	assert.NoError(t, w.DoRotate())
+	w.Destroy()
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "app.log."+today+".1000"))
+	assert.NoError(t, err)
+	assert.Equal(t, "first\n", string(b))
+}
+
+func TestDirect(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	config := `,"direct":true,"rotatename":"app-%Y%m%d.log","symlink":` + fmt.Sprintf("%q", filepath.Join(dir, "current.log"))
+	first := "app-" + time.Now().Format("20060102") + ".log"
+
+	w := newTestWriter(t, dir, config)
+	assert.NoError(t, w.WriteMsg("one", 4))
+	assert.NoError(t, w.DoRotate())
+	assert.NoError(t, w.WriteMsg("two", 4))
+	w.Destroy()
+
+	assert.Equal(t, []string{first, first + ".1", "current.log"}, listDir(t, dir))
+	target, err := os.Readlink(filepath.Join(dir, "current.log"))
+	assert.NoError(t, err)
+	assert.Equal(t, first+".1", target)
+
+	// restarted, the last file is appended to
+	w = newTestWriter(t, dir, config)
+	assert.NoError(t, w.WriteMsg("three", 4))
+	w.Destroy()
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "current.log"))
+	assert.NoError(t, err)
+	assert.Equal(t, "two\nthree\n", string(b))
+
+	segments, err := w.Segments()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{first}, segmentNames(segments))
+}
+
+func TestSymlinkNotReplacingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	current := filepath.Join(dir, "current.log")
+	assert.NoError(t, ioutil.WriteFile(current, []byte("kept\n"), 0660))
+
+	w := NewFileWriter()
+	err = w.Init(fmt.Sprintf(`{"filename":%q,"direct":true,"symlink":%q}`, filepath.Join(dir, "app.log"), current))
+	assert.EqualError(t, err, "hooks/file: "+current+" exists and is not a symlink")
+}
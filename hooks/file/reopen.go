@@ -0,0 +1,144 @@
+package file
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+)
+
+const defaultReopenCheck = time.Second
+
+// initReopen sets up the detection of the external rotations and starts the
+// signal handler.
+func (w *FileLogWriter) initReopen() error {
+	var err error
+	if w.reopenEvery, err = w.reopenInterval(); err != nil {
+		return err
+	}
+	if w.ReopenSignal != "" {
+		sig, ok := reopenSignals[w.ReopenSignal]
+		if !ok {
+			return fmt.Errorf("hooks/file: unsupported reopensignal %q", w.ReopenSignal)
+		}
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, sig)
+		w.signalStop = make(chan struct{})
+		go w.reopenOnSignal(signals, w.signalStop)
+	}
+	return nil
+}
+
+// reopenOnSignal reopens the file on each signal until stop is closed.
+func (w *FileLogWriter) reopenOnSignal(signals chan os.Signal, stop chan struct{}) {
+	defer signal.Stop(signals)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-signals:
+			if err := w.Reopen(); err != nil && err != ErrClosed {
+				fmt.Fprintf(os.Stderr, "FileLogWriter(%q): %s\n", w.Filename, err)
+			}
+		}
+	}
+}
+
+// Reopen closes the file written to and opens its path again, e.g. once
+// renamed by logrotate, without rotating it. The messages buffered are
+// written to the file opened.
+func (w *FileLogWriter) Reopen() error {
+	w.writing.RLock()
+	defer w.writing.RUnlock()
+	if w.closed {
+		return ErrClosed
+	}
+
+	w.rotateLock.Lock()
+	defer w.rotateLock.Unlock()
+	return w.reopen()
+}
+
+// reopen opens the path of the file written to again, under rotateLock.
+func (w *FileLogWriter) reopen() error {
+	w.mw.Lock()
+	defer w.mw.Unlock()
+	if err := w.startLogger(); err != nil {
+		return fmt.Errorf("hooks/file: reopening %s: %s", w.current, err)
+	}
+	return nil
+}
+
+// checkFile checks the file written to every ReopenCheck at most, see
+// checkPath, under rotateLock.
+func (w *FileLogWriter) checkFile(now time.Time) {
+	if w.reopenEvery == 0 || now.Before(w.nextCheck) {
+		return
+	}
+	w.nextCheck = now.Add(w.reopenEvery)
+	w.checkPath()
+}
+
+// checkPath reopens the file written to when its path names another file,
+// or none, having been renamed or removed by another process. When it was
+// truncated, e.g. by logrotate with copytruncate, the size and the lines
+// counted towards the rotation restart from its size. Under rotateLock.
+func (w *FileLogWriter) checkPath() {
+	fdInfo, err := w.mw.fd.Stat()
+	if err != nil {
+		return
+	}
+	info, err := os.Stat(w.current)
+	if (err != nil && os.IsNotExist(err)) || (err == nil && !os.SameFile(info, fdInfo)) {
+		if err := w.reopen(); err != nil {
+			fmt.Fprintf(os.Stderr, "FileLogWriter(%q): %s\n", w.Filename, err)
+		}
+		return
+	}
+
+	// the file only grows otherwise, the writes being done meanwhile
+	// included
+	if size := fdInfo.Size(); size < w.checkedSize {
+		w.maxsize_cursize = int(size)
+		w.maxlines_curlines = 0
+		w.checkedSize = size
+	} else {
+		w.checkedSize = size
+	}
+}
+
+// copyTruncate copies the file written to into name, then truncates it,
+// keeping it open, under the lock of mw: the processes having it open, or
+// reading it, go on with it.
+func (w *FileLogWriter) copyTruncate(name string) error {
+	src, err := os.Open(w.current)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dst, src); err == nil {
+		err = dst.Sync()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(name)
+		return err
+	}
+	os.Chtimes(name, info.ModTime(), info.ModTime())
+
+	if err = w.mw.fd.Truncate(0); err != nil {
+		return err
+	}
+	return w.initFd()
+}
@@ -0,0 +1,127 @@
+package file
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReopenWhenRenamed(t *testing.T) {
+	cases := []struct {
+		config           string
+		renamed, current string
+	}{
+		{`,"reopencheck":"1ns"`, "one\n", "two\nthree\n"},
+		// checked once the buffer is written
+		{`,"reopencheck":"1ns","asyncbuffer":true,"flushlevel":4`, "one\ntwo\n", "three\n"},
+	}
+	for _, c := range cases {
+		config := c.config
+		dir, err := ioutil.TempDir("", "logrus-file")
+		assert.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		w := newTestWriter(t, dir, config)
+		assert.NoError(t, w.WriteMsg("one", 4))
+		assert.NoError(t, os.Rename(w.Filename, w.Filename+".1"))
+		time.Sleep(time.Millisecond)
+		assert.NoError(t, w.WriteMsg("two", 4))
+		time.Sleep(time.Millisecond)
+		assert.NoError(t, w.WriteMsg("three", 4))
+		assert.NoError(t, w.Close())
+
+		assert.Equal(t, c.renamed, readFile(t, w.Filename+".1"), config)
+		assert.Equal(t, c.current, readFile(t, w.Filename), config)
+	}
+}
+
+func TestReopenWhenRemoved(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := newTestWriter(t, dir, `,"reopencheck":"1ns"`)
+	defer w.Close()
+	assert.NoError(t, w.WriteMsg("one", 4))
+	assert.NoError(t, os.Remove(w.Filename))
+	time.Sleep(time.Millisecond)
+	assert.NoError(t, w.WriteMsg("two", 4))
+	assert.Equal(t, "two\n", readFile(t, w.Filename))
+}
+
+func TestReopenCheckDisabled(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := newTestWriter(t, dir, `,"reopencheck":"0"`)
+	defer w.Close()
+	assert.NoError(t, w.WriteMsg("one", 4))
+	assert.NoError(t, os.Rename(w.Filename, w.Filename+".1"))
+	assert.NoError(t, w.WriteMsg("two", 4))
+	assert.Equal(t, "one\ntwo\n", readFile(t, w.Filename+".1"))
+
+	assert.NoError(t, w.Reopen())
+	assert.NoError(t, w.WriteMsg("three", 4))
+	assert.Equal(t, "three\n", readFile(t, w.Filename))
+
+	assert.NoError(t, w.Close())
+	assert.Equal(t, ErrClosed, w.Reopen())
+}
+
+func TestTruncated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := newTestWriter(t, dir, `,"reopencheck":"1ns","maxsize":20,"maxdays":0`)
+	defer w.Close()
+	assert.NoError(t, w.WriteMsg("0123456789", 4))
+	// copied then truncated by another process
+	assert.NoError(t, os.Truncate(w.Filename, 0))
+	time.Sleep(time.Millisecond)
+	assert.NoError(t, w.WriteMsg("abc", 4))
+	time.Sleep(time.Millisecond)
+	assert.NoError(t, w.WriteMsg("def", 4))
+
+	// appended without a hole, and not rotated as the size restarted
+	assert.Equal(t, "abc\ndef\n", readFile(t, w.Filename))
+	segments, err := w.Segments()
+	assert.NoError(t, err)
+	assert.Empty(t, segments)
+}
+
+func TestCopyTruncate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := newTestWriter(t, dir, `,"copytruncate":true,"maxdays":0`)
+	defer w.Close()
+	before, err := os.Stat(w.Filename)
+	assert.NoError(t, err)
+
+	assert.NoError(t, w.WriteMsg("one", 4))
+	assert.NoError(t, w.DoRotate())
+	assert.NoError(t, w.WriteMsg("two", 4))
+
+	after, err := os.Stat(w.Filename)
+	assert.NoError(t, err)
+	assert.True(t, os.SameFile(before, after))
+	assert.Equal(t, "two\n", readFile(t, w.Filename))
+
+	segments, err := w.Segments()
+	assert.NoError(t, err)
+	if assert.Len(t, segments, 1) {
+		assert.Equal(t, "one\n", readFile(t, segments[0].Path))
+	}
+
+	w2 := NewFileWriter()
+	err = w2.Init(fmt.Sprintf(`{"filename":%q,"copytruncate":true,"direct":true}`, filepath.Join(dir, "x.log")))
+	assert.EqualError(t, err, "hooks/file: copytruncate and direct can't both be set")
+}
@@ -0,0 +1,15 @@
+// +build !windows
+
+package file
+
+import (
+	"os"
+	"syscall"
+)
+
+// reopenSignals are the signals accepted as ReopenSignal.
+var reopenSignals = map[string]os.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+}
@@ -0,0 +1,40 @@
+// +build !windows
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReopenSignal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := newTestWriter(t, dir, `,"reopencheck":"0","reopensignal":"SIGUSR1"`)
+	defer w.Close()
+	assert.NoError(t, w.WriteMsg("one", 4))
+	assert.NoError(t, os.Rename(w.Filename, w.Filename+".1"))
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(w.Filename); err == nil {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.NoError(t, w.WriteMsg("two", 4))
+	assert.Equal(t, "one\n", readFile(t, w.Filename+".1"))
+	assert.Equal(t, "two\n", readFile(t, w.Filename))
+
+	w2 := NewFileWriter()
+	err = w2.Init(`{"filename":"app.log","reopensignal":"SIGKILL"}`)
+	assert.EqualError(t, err, `hooks/file: unsupported reopensignal "SIGKILL"`)
+}
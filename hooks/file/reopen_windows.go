@@ -0,0 +1,8 @@
+// +build windows
+
+package file
+
+import "os"
+
+// reopenSignals are the signals accepted as ReopenSignal, none on Windows.
+var reopenSignals = map[string]os.Signal{}
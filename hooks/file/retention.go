@@ -0,0 +1,134 @@
+package file
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Segment is a rotated file of a FileLogWriter.
+type Segment struct {
+	Path       string
+	Size       int64
+	ModTime    time.Time
+	Compressed bool
+
+	name segmentName
+}
+
+// Segments lists the rotated files of w, oldest first. Only the files named
+// by RotateName, compressed or not, in the directory of Filename are listed:
+// subdirectories, other files starting with the same name and the file
+// written to are not. Files being compressed are listed once, uncompressed
+// until compressed, e.g. by another process.
+func (w *FileLogWriter) Segments() ([]Segment, error) {
+	dir := filepath.Dir(w.Filename)
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("hooks/file: %s", err)
+	}
+	names := make(map[string]bool, len(infos))
+	for _, info := range infos {
+		names[info.Name()] = true
+	}
+
+	var segments []Segment
+	for _, info := range infos {
+		name, ok := w.names().parse(info.Name())
+		path := filepath.Join(dir, info.Name())
+		if !ok || !info.Mode().IsRegular() || name.tmp || path == w.current {
+			continue
+		}
+		if name.suffix == "" && compressedExists(names, info.Name()) {
+			// compressed, about to be removed
+			continue
+		}
+		segments = append(segments, Segment{
+			Path:       path,
+			Size:       info.Size(),
+			ModTime:    info.ModTime(),
+			Compressed: name.suffix != "",
+			name:       name,
+		})
+	}
+
+	sort.SliceStable(segments, func(i, j int) bool {
+		a, b := segments[i].name, segments[j].name
+		if a.key != b.key {
+			return a.key < b.key
+		}
+		return a.num < b.num
+	})
+	return segments, nil
+}
+
+// compressedExists reports whether the compressed file of the rotated file
+// name is among names.
+func compressedExists(names map[string]bool, name string) bool {
+	for _, suffix := range compressSuffixes {
+		if names[name+suffix] {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired lists the rotated files the retention would remove now, oldest
+// first, without removing them:
+//   - the ones modified more than Maxdays ago, when Maxdays is positive;
+//   - the ones beyond the MaxBackups newest, when MaxBackups is positive;
+//   - the oldest ones, until the remaining ones take at most MaxTotalSize
+//     bytes, when MaxTotalSize is positive.
+func (w *FileLogWriter) Expired() ([]Segment, error) {
+	segments, err := w.Segments()
+	if err != nil {
+		return nil, err
+	}
+
+	var deadline time.Time
+	if w.Maxdays > 0 {
+		deadline = time.Now().Add(-time.Duration(w.Maxdays) * 24 * time.Hour)
+	}
+
+	var expired []Segment
+	kept, total, full := 0, int64(0), false
+	for i := len(segments) - 1; i >= 0; i-- {
+		s := segments[i]
+		if w.MaxTotalSize > 0 && total+s.Size > w.MaxTotalSize {
+			// the older ones go too, even if smaller
+			full = true
+		}
+		switch {
+		case w.Maxdays > 0 && s.ModTime.Before(deadline),
+			w.MaxBackups > 0 && kept >= w.MaxBackups,
+			full:
+			expired = append(expired, s)
+		default:
+			kept++
+			total += s.Size
+		}
+	}
+
+	// oldest first
+	for i, j := 0, len(expired)-1; i < j; i, j = i+1, j-1 {
+		expired[i], expired[j] = expired[j], expired[i]
+	}
+	return expired, nil
+}
+
+// deleteOldLog removes the rotated files listed by Expired.
+func (w *FileLogWriter) deleteOldLog() {
+	expired, err := w.Expired()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "FileLogWriter(%q): %s\n", w.Filename, err)
+		return
+	}
+	for _, s := range expired {
+		if err := os.Remove(s.Path); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "FileLogWriter(%q): Unable to delete old log: %s\n", w.Filename, err)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func segmentNames(segments []Segment) []string {
+	var names []string
+	for _, s := range segments {
+		names = append(names, filepath.Base(s.Path))
+	}
+	return names
+}
+
+func TestRetention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(name string, size int) {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0770))
+		assert.NoError(t, ioutil.WriteFile(path, make([]byte, size), 0660))
+	}
+	write("app.log", 10)
+	write("app.log.2017-07-01.001.gz", 10)
+	write("app.log.2017-07-01.002", 10)
+	write("app.log.2017-07-02.010", 10)
+	write("app.log.2017-07-02.002.zz", 10)
+	write("app.log.2017-07-03.001.gz.tmp", 10)
+	write("app.log-archive/app.log.2017-07-01.001", 10)
+	write("app.logger.txt", 10)
+	write("app.log.old", 10)
+
+	w := NewFileWriter().(*FileLogWriter)
+	w.Filename = filepath.Join(dir, "app.log")
+	w.Maxdays = 0
+
+	segments, err := w.Segments()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"app.log.2017-07-01.001.gz",
+		"app.log.2017-07-01.002",
+		"app.log.2017-07-02.002.zz",
+		"app.log.2017-07-02.010",
+	}, segmentNames(segments))
+	assert.True(t, segments[0].Compressed)
+	assert.False(t, segments[1].Compressed)
+	assert.Equal(t, int64(10), segments[0].Size)
+
+	expired, err := w.Expired()
+	assert.NoError(t, err)
+	assert.Empty(t, expired)
+
+	w.MaxBackups = 3
+	expired, err = w.Expired()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"app.log.2017-07-01.001.gz"}, segmentNames(expired))
+
+	w.MaxTotalSize = 25
+	expired, err = w.Expired()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"app.log.2017-07-01.001.gz", "app.log.2017-07-01.002"}, segmentNames(expired))
+
+	w.MaxBackups, w.MaxTotalSize = 0, 0
+	w.Maxdays = 7
+	old := time.Now().Add(-8 * 24 * time.Hour)
+	assert.NoError(t, os.Chtimes(filepath.Join(dir, "app.log.2017-07-02.002.zz"), old, old))
+	expired, err = w.Expired()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"app.log.2017-07-02.002.zz"}, segmentNames(expired))
+
+	w.MaxBackups = 2
+	w.deleteOldLog()
+	assert.Equal(t, []string{
+		"app.log",
+		"app.log-archive",
+		"app.log.2017-07-01.002",
+		"app.log.2017-07-02.010",
+		"app.log.2017-07-03.001.gz.tmp",
+		"app.log.old",
+		"app.logger.txt",
+	}, listDir(t, dir))
+	assert.Equal(t, []string{"app.log.2017-07-01.001"}, listDir(t, filepath.Join(dir, "app.log-archive")))
+}
+
+func TestRetentionTotalSizeOldestFirst(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for name, size := range map[string]int{
+		"app.log.2017-07-01.001": 5,
+		"app.log.2017-07-01.002": 100,
+		"app.log.2017-07-01.003": 10,
+	} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), make([]byte, size), 0660))
+	}
+	w := NewFileWriter().(*FileLogWriter)
+	w.Filename = filepath.Join(dir, "app.log")
+	w.Maxdays = 0
+	w.MaxTotalSize = 50
+
+	// the small 001 is older than the large 002 expired
+	expired, err := w.Expired()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"app.log.2017-07-01.001", "app.log.2017-07-01.002"}, segmentNames(expired))
+}
+
+func TestRetentionWhileCompressing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// 001 is compressed, but not removed yet
+	for _, name := range []string{"app.log.2017-07-01.000.gz", "app.log.2017-07-01.001", "app.log.2017-07-01.001.gz", "app.log.2017-07-01.002"} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte("x"), 0660))
+	}
+	w := NewFileWriter().(*FileLogWriter)
+	w.Filename = filepath.Join(dir, "app.log")
+	w.Maxdays = 0
+	w.MaxBackups = 3
+
+	segments, err := w.Segments()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"app.log.2017-07-01.000.gz", "app.log.2017-07-01.001.gz", "app.log.2017-07-01.002"}, segmentNames(segments))
+	expired, err := w.Expired()
+	assert.NoError(t, err)
+	assert.Empty(t, expired)
+}
+
+func TestRetentionOfCompressedRotations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := newTestWriter(t, dir, `,"compress":"gzip","maxbackups":5,"maxdays":0`)
+	for i := 0; i < 40; i++ {
+		assert.NoError(t, w.WriteMsg("line", 4))
+		assert.NoError(t, w.DoRotate())
+	}
+	assert.NoError(t, w.Close())
+
+	segments, err := w.Segments()
+	assert.NoError(t, err)
+	assert.Len(t, segments, 5)
+	for _, s := range segments {
+		assert.True(t, s.Compressed, s.Path)
+	}
+}
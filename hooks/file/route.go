@@ -0,0 +1,364 @@
+package file
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/logrus"
+)
+
+// Route selects the entries written to the file named by Filename. The
+// conditions set must all hold, a Route without conditions matching any
+// entry.
+type Route struct {
+	// Levels of the entries, any level if empty.
+	Levels []logrus.Level
+
+	// Field the entries must have, with one of Values, formatted with
+	// fmt.Sprint, or with any value if Values is empty.
+	Field  string
+	Values []string
+
+	// Logger is the Name of the logger of the entries.
+	Logger string
+
+	// Filename of the file, where {name} is replaced with the value of the
+	// field name of the entry, e.g. "logs/{tenant}/app.log". The entries
+	// without the fields of the template don't match.
+	Filename string
+
+	// Config is the JSON configuration of the writers of the route, see
+	// FileLogWriter.Init, set over the Config of the hook. The filename is
+	// set from Filename.
+	Config string
+
+	// Continue with the next routes once matched, e.g. to write the errors
+	// in app.log too. Otherwise an entry is written by the first route
+	// matching it.
+	Continue bool
+
+	filename []templatePart
+	levels   uint64 // bit set, all levels if 0
+}
+
+// templatePart is a literal, or the name of a field with field.
+type templatePart struct {
+	text  string
+	field bool
+}
+
+// parseFilenameTemplate splits s into literals and {field} references.
+func parseFilenameTemplate(s string) ([]templatePart, error) {
+	var parts []templatePart
+	for s != "" {
+		open := strings.IndexByte(s, '{')
+		if close := strings.IndexByte(s, '}'); close >= 0 && (open < 0 || close < open) {
+			return nil, fmt.Errorf("unbalanced }")
+		}
+		if open < 0 {
+			parts = append(parts, templatePart{text: s})
+			break
+		}
+		if open > 0 {
+			parts = append(parts, templatePart{text: s[:open]})
+		}
+		s = s[open+1:]
+		close := strings.IndexByte(s, '}')
+		if close < 0 {
+			return nil, fmt.Errorf("unbalanced {")
+		}
+		if close == 0 || strings.IndexByte(s[:close], '{') >= 0 {
+			return nil, fmt.Errorf("invalid field name %q", s[:close])
+		}
+		parts = append(parts, templatePart{text: s[:close], field: true})
+		s = s[close+1:]
+	}
+	return parts, nil
+}
+
+// pathElement makes a field value safe as a part of a path: characters other
+// than letters, digits, '-', '_' and '.' are replaced with '_', so that it
+// can't name another directory.
+func pathElement(value string) string {
+	b := []byte(value)
+	for i, c := range b {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.') {
+			b[i] = '_'
+		}
+	}
+	if s := string(b); s != "" && strings.Trim(s, ".") != "" {
+		return s
+	}
+	return "_"
+}
+
+// match reports whether the route selects entry, with the name of its file.
+func (r *Route) match(entry *logrus.Entry) (string, bool) {
+	if r.levels != 0 && r.levels&(1<<uint(entry.Level)) == 0 {
+		return "", false
+	}
+	if r.Logger != "" && (entry.Logger == nil || entry.Logger.Name != r.Logger) {
+		return "", false
+	}
+	if r.Field != "" {
+		value, ok := entry.Data[r.Field]
+		if !ok {
+			return "", false
+		}
+		if len(r.Values) > 0 {
+			s, found := fmt.Sprint(value), false
+			for _, v := range r.Values {
+				if s == v {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return "", false
+			}
+		}
+	}
+
+	var name []byte
+	for _, part := range r.filename {
+		if !part.field {
+			name = append(name, part.text...)
+			continue
+		}
+		value, ok := entry.Data[part.text]
+		if !ok {
+			return "", false
+		}
+		name = append(name, pathElement(fmt.Sprint(value))...)
+	}
+	return string(name), true
+}
+
+// RoutingHook writes the entries to files selected by Routes, each file
+// rotated and pruned on its own by a FileLogWriter. At most MaxOpen writers
+// are kept open, the least recently used being closed.
+type RoutingHook struct {
+	PrintFormat string
+
+	// Config is the JSON configuration of the writers, see
+	// FileLogWriter.Init, without filename.
+	Config string
+
+	Routes []Route
+
+	// MaxOpen is the number of files kept open, 64 by default.
+	MaxOpen int
+
+	// Sanitize and Location are as for FileHook.
+	Sanitize logrus.SanitizePolicy
+	Location *time.Location
+
+	mu      sync.Mutex
+	closed  bool
+	writers map[string]*list.Element // of *routeTarget, in lru
+	lru     *list.List               // most recently used first
+}
+
+type routeTarget struct {
+	filename string
+	w        *FileLogWriter
+}
+
+const defaultMaxOpen = 64
+
+// NewRoutingHook returns a hook writing the entries formatted with
+// printFormat to the files of the first route matching them, e.g. for the
+// errors in error.log, the access logs in access.log and the rest in app.log:
+//
+//	hook, err := file.NewRoutingHook(`{"maxdays":15}`, "[%s] [%L] %M",
+//		file.Route{Levels: []logrus.Level{logrus.ErrorLevel}, Filename: "logs/error.log"},
+//		file.Route{Field: "type", Values: []string{"access"}, Filename: "logs/access.log"},
+//		file.Route{Filename: "logs/app.log"},
+//	)
+//
+// The files are opened on the first entry written to them.
+func NewRoutingHook(config, printFormat string, routes ...Route) (*RoutingHook, error) {
+	hook := &RoutingHook{
+		PrintFormat: printFormat,
+		Config:      config,
+		Routes:      routes,
+		MaxOpen:     defaultMaxOpen,
+	}
+	if err := hook.init(); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// init checks the routes and the configurations.
+func (hook *RoutingHook) init() error {
+	if len(hook.Routes) == 0 {
+		return errors.New("hooks/file: no routes")
+	}
+	base := DefaultConfig()
+	if hook.Config != "" {
+		if err := decodeConfig(hook.Config, &base); err != nil {
+			return err
+		}
+	}
+	for i := range hook.Routes {
+		r := &hook.Routes[i]
+		if r.Filename == "" {
+			return fmt.Errorf("hooks/file: route %d has no filename", i)
+		}
+		var err error
+		if r.filename, err = parseFilenameTemplate(r.Filename); err != nil {
+			return fmt.Errorf("hooks/file: route %d: invalid filename %q: %s", i, r.Filename, err)
+		}
+		cfg := base
+		if r.Config != "" {
+			if err := decodeConfig(r.Config, &cfg); err != nil {
+				return fmt.Errorf("hooks/file: route %d: %s", i, strings.TrimPrefix(err.Error(), "hooks/file: "))
+			}
+		}
+		cfg.Filename = r.Filename
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("hooks/file: route %d: %s", i, strings.TrimPrefix(err.Error(), "hooks/file: "))
+		}
+		r.levels = 0
+		for _, level := range r.Levels {
+			r.levels |= 1 << uint(level)
+		}
+	}
+	hook.writers = make(map[string]*list.Element)
+	hook.lru = list.New()
+	return nil
+}
+
+func (hook *RoutingHook) Fire(entry *logrus.Entry) error {
+	var message string
+	formatted := false
+	for i := range hook.Routes {
+		r := &hook.Routes[i]
+		filename, ok := r.match(entry)
+		if !ok {
+			continue
+		}
+		if !formatted {
+			record := logrus.PrepareEntry(entry)
+			printFormat := logrus.NewPrintFormat(hook.PrintFormat)
+			printFormat.Sanitize = hook.Sanitize
+			printFormat.Location = hook.Location
+			message = printFormat.Format(record)
+			formatted = true
+		}
+		if err := hook.write(r, filename, message, int(entry.Level)); err != nil {
+			return err
+		}
+		if !r.Continue {
+			break
+		}
+	}
+	return nil
+}
+
+// write writes msg with the writer of filename, opening it if need be. The
+// writer may be closed by another entry meanwhile, to make room: it is
+// opened again then.
+func (hook *RoutingHook) write(r *Route, filename, msg string, level int) error {
+	for {
+		w, err := hook.writer(r, filename)
+		if err != nil {
+			return err
+		}
+		if err = w.WriteMsg(msg, level); err != ErrClosed {
+			return err
+		}
+	}
+}
+
+// writer returns the open writer of filename, making it the most recently
+// used, or opens it, closing the least recently used if MaxOpen are open.
+func (hook *RoutingHook) writer(r *Route, filename string) (*FileLogWriter, error) {
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+	if hook.closed {
+		return nil, ErrClosed
+	}
+	if e, ok := hook.writers[filename]; ok {
+		hook.lru.MoveToFront(e)
+		return e.Value.(*routeTarget).w, nil
+	}
+
+	max := hook.MaxOpen
+	if max <= 0 {
+		max = defaultMaxOpen
+	}
+	for hook.lru.Len() >= max {
+		hook.closeTarget(hook.lru.Back())
+	}
+
+	cfg := DefaultConfig()
+	for _, config := range []string{hook.Config, r.Config} {
+		if config != "" {
+			if err := decodeConfig(config, &cfg); err != nil {
+				return nil, err
+			}
+		}
+	}
+	cfg.Filename = filename
+	w, err := OpenWriter(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("hooks/file: opening %s: %s", filename, err)
+	}
+	hook.writers[filename] = hook.lru.PushFront(&routeTarget{filename: filename, w: w})
+	return w, nil
+}
+
+// closeTarget closes the writer of e and forgets it, under mu.
+func (hook *RoutingHook) closeTarget(e *list.Element) error {
+	target := hook.lru.Remove(e).(*routeTarget)
+	delete(hook.writers, target.filename)
+	return target.w.Close()
+}
+
+// Open returns the number of files open.
+func (hook *RoutingHook) Open() int {
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+	return hook.lru.Len()
+}
+
+// Flush flushes the writers open.
+func (hook *RoutingHook) Flush() {
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+	for e := hook.lru.Front(); e != nil; e = e.Next() {
+		e.Value.(*routeTarget).w.Flush()
+	}
+}
+
+// Close closes the writers, writing everything buffered. Entries fired
+// afterwards return ErrClosed.
+func (hook *RoutingHook) Close() error {
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+	hook.closed = true
+	var first error
+	for hook.lru.Len() > 0 {
+		if err := hook.closeTarget(hook.lru.Front()); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (hook *RoutingHook) Levels() []logrus.Level {
+	return []logrus.Level{
+		logrus.PanicLevel,
+		logrus.FatalLevel,
+		logrus.ErrorLevel,
+		logrus.WarnLevel,
+		logrus.InfoLevel,
+		logrus.DebugLevel,
+	}
+}
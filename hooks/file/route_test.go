@@ -0,0 +1,155 @@
+package file
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRoutedLogger(t *testing.T, config string, routes ...Route) (*logrus.Logger, *RoutingHook) {
+	hook, err := NewRoutingHook(config, "%L %M", routes...)
+	assert.NoError(t, err)
+	log := logrus.New()
+	log.Out = ioutil.Discard
+	log.Level = logrus.DebugLevel
+	log.Hooks.Add(hook)
+	return log, hook
+}
+
+func readFile(t *testing.T, name string) string {
+	b, err := ioutil.ReadFile(name)
+	assert.NoError(t, err)
+	return string(b)
+}
+
+func TestRouteByLevelAndField(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	log, hook := newRoutedLogger(t, `{"daily":false,"level":5}`,
+		Route{Levels: []logrus.Level{logrus.ErrorLevel, logrus.FatalLevel}, Filename: filepath.Join(dir, "error.log"), Continue: true},
+		Route{Field: "type", Values: []string{"access"}, Filename: filepath.Join(dir, "access.log")},
+		Route{Logger: "audit", Filename: filepath.Join(dir, "audit.log")},
+		Route{Filename: filepath.Join(dir, "app.log")},
+	)
+	log.Info("started")
+	log.WithField("type", "access").Info("GET /")
+	log.WithField("type", "job").Debug("tick")
+	log.Error("failed")
+	log.Name = "audit"
+	log.Warn("login")
+	assert.NoError(t, hook.Close())
+
+	assert.Equal(t, "ERROR failed\n", readFile(t, filepath.Join(dir, "error.log")))
+	assert.Equal(t, "INFO GET /\n", readFile(t, filepath.Join(dir, "access.log")))
+	assert.Equal(t, "WARN login\n", readFile(t, filepath.Join(dir, "audit.log")))
+	assert.Equal(t, "INFO started\nDEBUG tick\nERROR failed\n", readFile(t, filepath.Join(dir, "app.log")))
+
+	_, err = hook.writer(&hook.Routes[0], filepath.Join(dir, "error.log"))
+	assert.Equal(t, ErrClosed, err)
+}
+
+func TestRouteTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	log, hook := newRoutedLogger(t, `{"daily":false}`,
+		Route{Filename: filepath.Join(dir, "{tenant}", "app.log")},
+		Route{Filename: filepath.Join(dir, "app.log")},
+	)
+	log.WithField("tenant", "acme").Info("one")
+	log.WithField("tenant", 42).Info("two")
+	log.WithField("tenant", "../../etc").Info("three")
+	log.Info("four")
+	assert.NoError(t, hook.Close())
+
+	assert.Equal(t, "INFO one\n", readFile(t, filepath.Join(dir, "acme", "app.log")))
+	assert.Equal(t, "INFO two\n", readFile(t, filepath.Join(dir, "42", "app.log")))
+	assert.Equal(t, "INFO three\n", readFile(t, filepath.Join(dir, ".._.._etc", "app.log")))
+	assert.Equal(t, "INFO four\n", readFile(t, filepath.Join(dir, "app.log")))
+}
+
+func TestRouteMaxOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	log, hook := newRoutedLogger(t, `{"daily":false,"maxlines":3,"maxdays":0}`,
+		Route{Filename: filepath.Join(dir, "{tenant}.log")},
+	)
+	hook.MaxOpen = 2
+	tenants := []string{"a", "b", "c"}
+	for i := 0; i < 12; i++ {
+		log.WithField("tenant", tenants[i%3]).Info(i)
+		assert.True(t, hook.Open() <= 2)
+	}
+	assert.NoError(t, hook.Close())
+	assert.Equal(t, 0, hook.Open())
+
+	// each file is rotated on its own, across reopening
+	for i, tenant := range tenants {
+		w := newFileLogWriter()
+		w.Filename = filepath.Join(dir, tenant+".log")
+		lines := readLines(t, w)
+		assert.Equal(t, []string{
+			fmt.Sprintf("INFO %d", i), fmt.Sprintf("INFO %d", i+3),
+			fmt.Sprintf("INFO %d", i+6), fmt.Sprintf("INFO %d", i+9),
+		}, lines, tenant)
+		segments, err := w.Segments()
+		assert.NoError(t, err)
+		assert.Len(t, segments, 1, tenant)
+	}
+}
+
+func TestRouteMaxOpenConcurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	log, hook := newRoutedLogger(t, `{"daily":false,"rotate":false}`,
+		Route{Filename: filepath.Join(dir, "{tenant}.log")},
+	)
+	hook.MaxOpen = 1
+	tenants := []string{"a", "b", "c", "d"}
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				log.WithField("tenant", tenants[(g+i)%len(tenants)]).Info(i)
+			}
+		}(g)
+	}
+	wg.Wait()
+	assert.NoError(t, hook.Close())
+
+	// no line is lost when a writer is closed while written to
+	total := 0
+	for _, tenant := range tenants {
+		total += strings.Count(readFile(t, filepath.Join(dir, tenant+".log")), "\n")
+	}
+	assert.Equal(t, 16*100, total)
+}
+
+func TestRouteConfig(t *testing.T) {
+	_, err := NewRoutingHook("", "%M")
+	assert.Error(t, err)
+
+	for _, filename := range []string{"", "logs/{tenant.log", "logs/tenant}.log", "logs/{}.log", "logs/{a{b}}.log"} {
+		_, err = NewRoutingHook("", "%M", Route{Filename: filename})
+		assert.Error(t, err, filename)
+	}
+
+	_, err = NewRoutingHook(`{"maxlines":"many"}`, "%M", Route{Filename: "app.log"})
+	assert.True(t, err != nil && strings.HasPrefix(err.Error(), "hooks/file: invalid config"), "%v", err)
+}
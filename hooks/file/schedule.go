@@ -0,0 +1,260 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rotationSchedule gives the times of the scheduled rotations.
+type rotationSchedule interface {
+	// next returns the first rotation after t, or the zero time if none.
+	next(t time.Time) time.Time
+}
+
+// intervalSchedule rotates every interval, aligned on the wall clock of loc:
+// from midnight for intervals shorter than a day, from Monday midnight for
+// intervals of whole days.
+type intervalSchedule struct {
+	every time.Duration
+	loc   *time.Location
+}
+
+// mondayEpoch is the Monday the intervals of whole days are aligned from.
+var mondayEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const day = 24 * time.Hour
+
+var intervalNames = map[string]time.Duration{
+	"hourly": time.Hour,
+	"daily":  day,
+	"weekly": 7 * day,
+}
+
+// parseInterval parses a duration such as "15m" or "168h", or "hourly",
+// "daily" or "weekly".
+func parseInterval(s string, loc *time.Location) (rotationSchedule, error) {
+	every, ok := intervalNames[s]
+	if !ok {
+		var err error
+		if every, err = time.ParseDuration(s); err != nil {
+			return nil, fmt.Errorf("hooks/file: invalid rotateinterval %q: %s", s, err)
+		}
+	}
+	if every < time.Second {
+		return nil, fmt.Errorf("hooks/file: invalid rotateinterval %q, less than a second", s)
+	}
+	if every > day && every%day != 0 {
+		return nil, fmt.Errorf("hooks/file: invalid rotateinterval %q, longer than a day but not whole days", s)
+	}
+	return &intervalSchedule{every: every, loc: loc}, nil
+}
+
+func (s *intervalSchedule) next(t time.Time) time.Time {
+	t = t.In(s.loc)
+	y, m, d := t.Date()
+
+	var next time.Time
+	if s.every < day {
+		since := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+			time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+		if offset := (since/s.every + 1) * s.every; offset < day {
+			// on the wall clock, across daylight saving time changes
+			next = time.Date(y, m, d, int(offset/time.Hour), int(offset%time.Hour/time.Minute),
+				int(offset%time.Minute/time.Second), int(offset%time.Second), s.loc)
+		} else {
+			next = time.Date(y, m, d+1, 0, 0, 0, 0, s.loc)
+		}
+	} else {
+		days := int(s.every / day)
+		index := int(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Sub(mondayEpoch) / day)
+		index = (index/days + 1) * days
+		next = time.Date(mondayEpoch.Year(), mondayEpoch.Month(), mondayEpoch.Day()+index, 0, 0, 0, 0, s.loc)
+	}
+
+	for !next.After(t) {
+		next = next.Add(s.every)
+	}
+	return next
+}
+
+// cronSchedule rotates at the minutes matching a cron expression, on the
+// wall clock of loc.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64 // bit sets
+	domStar, dowStar              bool
+	loc                           *time.Location
+}
+
+var cronShortcuts = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 1",
+	"@monthly": "0 0 1 * *",
+}
+
+// parseCron parses "minute hour day-of-month month day-of-week", each field
+// being *, a number, a range a-b, a step */n or a-b/n, or a list of them
+// separated with commas. Days of week go from 0, Sunday, to 6, or 7 for
+// Sunday again. @hourly, @daily, @weekly (on Mondays) and @monthly are
+// accepted too. As with cron, when both days are restricted either matches.
+func parseCron(s string, loc *time.Location) (rotationSchedule, error) {
+	if shortcut, ok := cronShortcuts[s]; ok {
+		s = shortcut
+	}
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("hooks/file: invalid rotateschedule %q, need 5 fields", s)
+	}
+
+	c := &cronSchedule{loc: loc}
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	sets := [5]*uint64{&c.minute, &c.hour, &c.dom, &c.month, &c.dow}
+	for i, field := range fields {
+		set, err := parseCronField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("hooks/file: invalid rotateschedule %q: %s", s, err)
+		}
+		*sets[i] = set
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domStar = fields[2] == "*"
+	c.dowStar = fields[4] == "*"
+	return c, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		from, to, step := min, max, 1
+		rangePart := part
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart = part[:i]
+		}
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if from, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid %q", part)
+			}
+			to = from
+			if len(bounds) == 2 {
+				if to, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid %q", part)
+				}
+			} else if step > 1 {
+				to = max
+			}
+		}
+		if from < min || to > max || from > to {
+			return 0, fmt.Errorf("%q out of %d-%d", part, min, max)
+		}
+		for v := from; v <= to; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func (c *cronSchedule) next(t time.Time) time.Time {
+	t = t.In(c.loc)
+	limit := t.AddDate(5, 0, 0)
+	y, m, d := t.Date()
+	t = time.Date(y, m, d, t.Hour(), t.Minute()+1, 0, 0, c.loc)
+
+	for t.Before(limit) {
+		y, m, d = t.Date()
+		switch {
+		case c.month&(1<<uint(m)) == 0:
+			t = time.Date(y, m+1, 1, 0, 0, 0, 0, c.loc)
+		case !c.dayMatches(t):
+			t = time.Date(y, m, d+1, 0, 0, 0, 0, c.loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(y, m, d, t.Hour()+1, 0, 0, 0, c.loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// initSchedule sets the location and the schedule of the rotations from the
+// configuration.
+func (w *FileLogWriter) initSchedule() error {
+	var err error
+	if w.loc, err = w.location(); err != nil {
+		return err
+	}
+	w.schedule, err = w.rotationSchedule(w.loc)
+	return err
+}
+
+// rotateOnSchedule rotates at the scheduled times until stop is closed. Empty
+// files are left as they are.
+func (w *FileLogWriter) rotateOnSchedule(stop chan struct{}) {
+	for {
+		w.rotateLock.Lock()
+		next := w.nextRotation
+		w.rotateLock.Unlock()
+		if next.IsZero() {
+			return
+		}
+
+		timer := time.NewTimer(next.Sub(time.Now()))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		w.rotateLock.Lock()
+		select {
+		case <-stop:
+			w.rotateLock.Unlock()
+			return
+		default:
+		}
+		unlock, err := w.lockShared()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "FileLogWriter(%q): %s\n", w.Filename, err)
+			unlock = func() {}
+		}
+		now := time.Now()
+		if err == nil && !w.nextRotation.IsZero() && !now.Before(w.nextRotation) && w.maxsize_cursize > 0 {
+			if err := w.DoRotate(); err != nil {
+				fmt.Fprintf(os.Stderr, "FileLogWriter(%q): %s\n", w.Filename, err)
+			}
+		}
+		w.skipRotation(now)
+		unlock()
+		w.rotateLock.Unlock()
+	}
+}
+
+// skipRotation moves the next rotation after now when it is due but the file
+// wasn't rotated, e.g. because it is empty or rotating it failed.
+func (w *FileLogWriter) skipRotation(now time.Time) {
+	if !w.nextRotation.IsZero() && !now.Before(w.nextRotation) {
+		w.nextRotation = w.schedule.next(now)
+	}
+}
@@ -0,0 +1,103 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var cst = time.FixedZone("CST", 8*3600)
+
+func TestIntervalSchedule(t *testing.T) {
+	cases := []struct {
+		interval string
+		from     time.Time
+		next     time.Time
+	}{
+		{"15m", time.Date(2017, 7, 5, 10, 7, 30, 0, cst), time.Date(2017, 7, 5, 10, 15, 0, 0, cst)},
+		{"15m", time.Date(2017, 7, 5, 10, 15, 0, 0, cst), time.Date(2017, 7, 5, 10, 30, 0, 0, cst)},
+		{"hourly", time.Date(2017, 7, 5, 23, 30, 0, 0, cst), time.Date(2017, 7, 6, 0, 0, 0, 0, cst)},
+		{"7m", time.Date(2017, 7, 5, 23, 59, 0, 0, cst), time.Date(2017, 7, 6, 0, 0, 0, 0, cst)},
+		{"daily", time.Date(2017, 7, 31, 18, 0, 0, 0, cst), time.Date(2017, 8, 1, 0, 0, 0, 0, cst)},
+		{"weekly", time.Date(2017, 7, 5, 18, 0, 0, 0, cst), time.Date(2017, 7, 10, 0, 0, 0, 0, cst)},
+		// boundaries are in the location, not in the location of the time
+		{"daily", time.Date(2017, 7, 5, 18, 0, 0, 0, time.UTC), time.Date(2017, 7, 7, 0, 0, 0, 0, cst)},
+	}
+	for _, c := range cases {
+		s, err := parseInterval(c.interval, cst)
+		assert.NoError(t, err)
+		assert.True(t, c.next.Equal(s.next(c.from)), "%s from %s: %s", c.interval, c.from, s.next(c.from))
+	}
+
+	for _, interval := range []string{"", "soon", "10ms", "36h"} {
+		_, err := parseInterval(interval, cst)
+		assert.Error(t, err, interval)
+	}
+}
+
+func TestCronSchedule(t *testing.T) {
+	friday := time.Date(2017, 7, 7, 1, 0, 0, 0, cst)
+	cases := []struct {
+		schedule string
+		from     time.Time
+		next     time.Time
+	}{
+		{"*/15 * * * *", friday, time.Date(2017, 7, 7, 1, 15, 0, 0, cst)},
+		{"0 */6 * * *", friday, time.Date(2017, 7, 7, 6, 0, 0, 0, cst)},
+		{"30 0 * * 1-5", friday, time.Date(2017, 7, 10, 0, 30, 0, 0, cst)},
+		{"0 0 1 * *", friday, time.Date(2017, 8, 1, 0, 0, 0, 0, cst)},
+		{"@weekly", friday, time.Date(2017, 7, 10, 0, 0, 0, 0, cst)},
+		{"0 12 * * 7", friday, time.Date(2017, 7, 9, 12, 0, 0, 0, cst)},
+		// either day matches
+		{"0 0 13 * 5", time.Date(2017, 7, 8, 0, 0, 0, 0, cst), time.Date(2017, 7, 13, 0, 0, 0, 0, cst)},
+		{"0 0 29 2 *", friday, time.Date(2020, 2, 29, 0, 0, 0, 0, cst)},
+		{"0 0 31 2 *", friday, time.Time{}},
+	}
+	for _, c := range cases {
+		s, err := parseCron(c.schedule, cst)
+		assert.NoError(t, err)
+		assert.True(t, c.next.Equal(s.next(c.from)), "%s: %s", c.schedule, s.next(c.from))
+	}
+
+	for _, schedule := range []string{"", "* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := parseCron(schedule, cst)
+		assert.Error(t, err, schedule)
+	}
+}
+
+func TestRotateOnScheduleWhenIdle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := newTestWriter(t, dir, `,"rotateinterval":"1s","maxdays":0`)
+	defer w.Destroy()
+	assert.NoError(t, w.WriteMsg("first", 4))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(listDir(t, dir)) < 2 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	names := listDir(t, dir)
+	if assert.Len(t, names, 2) {
+		assert.True(t, strings.HasPrefix(names[1], "app.log."), names[1])
+	}
+
+	// the empty file is left as is
+	time.Sleep(1200 * time.Millisecond)
+	assert.Len(t, listDir(t, dir), 2)
+}
+
+func TestScheduleConfig(t *testing.T) {
+	w := NewFileWriter()
+	err := w.Init(`{"filename":"app.log","rotateinterval":"1h","rotateschedule":"@daily"}`)
+	assert.EqualError(t, err, "hooks/file: rotateinterval and rotateschedule can't both be set")
+
+	w = NewFileWriter()
+	err = w.Init(`{"filename":"app.log","timezone":"Mars/Olympus_Mons"}`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,75 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// initShared opens the lock file of the processes writing to Filename.
+func (w *FileLogWriter) initShared() error {
+	if !w.Shared {
+		return nil
+	}
+	if w.LockFile == "" {
+		w.LockFile = w.Filename + ".lock"
+	}
+	if err := w.makeDir(w.LockFile); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.LockFile, os.O_RDWR|os.O_CREATE, os.FileMode(w.FileMode))
+	if err != nil {
+		return fmt.Errorf("hooks/file: %s", err)
+	}
+	w.lockFd = f
+	return nil
+}
+
+// lockShared takes the lock of the processes writing to Filename, under
+// rotateLock, and reopens the file if another process rotated it. The size
+// of the file, written by all of them, is counted towards Maxsize. It
+// returns the function releasing the lock, doing nothing without Shared.
+func (w *FileLogWriter) lockShared() (func(), error) {
+	if !w.Shared {
+		return func() {}, nil
+	}
+	if err := lockFile(w.lockFd); err != nil {
+		return nil, fmt.Errorf("hooks/file: locking %s: %s", w.LockFile, err)
+	}
+	unlock := func() {
+		if err := unlockFile(w.lockFd); err != nil {
+			fmt.Fprintf(os.Stderr, "FileLogWriter(%q): unlocking %s: %s\n", w.Filename, w.LockFile, err)
+		}
+	}
+
+	w.checkPath()
+	info, err := w.mw.fd.Stat()
+	if err != nil {
+		unlock()
+		return nil, fmt.Errorf("hooks/file: %s", err)
+	}
+	w.maxsize_cursize = int(info.Size())
+	return unlock, nil
+}
+
+// writeShared writes p to the file with the other processes, rotating it
+// before if need be: p lands whole in a single file.
+func (w *FileLogWriter) writeShared(p []byte) (int, error) {
+	w.rotateLock.Lock()
+	defer w.rotateLock.Unlock()
+	unlock, err := w.lockShared()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	now := time.Now()
+	if w.Rotate && ((w.Maxsize > 0 && w.maxsize_cursize >= w.Maxsize) ||
+		(!w.nextRotation.IsZero() && !now.Before(w.nextRotation))) {
+		if err := w.DoRotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "FileLogWriter(%q): %s\n", w.Filename, err)
+		}
+		w.skipRotation(now)
+	}
+	return w.mw.Write(p)
+}
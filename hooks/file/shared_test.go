@@ -0,0 +1,117 @@
+// +build !windows
+
+package file
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sharedChildEnv = "LOGRUS_FILE_SHARED_CHILD"
+
+// TestSharedChild writes messages as one of the processes of
+// TestSharedRotation.
+func TestSharedChild(t *testing.T) {
+	config := os.Getenv(sharedChildEnv)
+	if config == "" {
+		t.Skip("run by TestSharedRotation")
+	}
+	id, _ := strconv.Atoi(os.Getenv(sharedChildEnv + "_ID"))
+	messages, _ := strconv.Atoi(os.Getenv(sharedChildEnv + "_MESSAGES"))
+
+	w := NewFileWriter().(*FileLogWriter)
+	if !assert.NoError(t, w.Init(config)) {
+		return
+	}
+	padding := strings.Repeat("x", 10*id)
+	for i := 0; i < messages; i++ {
+		msg := fmt.Sprintf("p%d %05d %s", id, i, padding)
+		if i%2 == 0 {
+			assert.NoError(t, w.WriteMsg(msg, 4))
+		} else {
+			_, err := w.Write([]byte(msg + "\n"))
+			assert.NoError(t, err)
+		}
+	}
+	assert.NoError(t, w.Close())
+}
+
+func TestSharedRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	const children, messages, maxsize = 4, 500, 4096
+	filename := filepath.Join(dir, "app.log")
+	var cmds []*exec.Cmd
+	var outputs []*bytes.Buffer
+	for id := 0; id < children; id++ {
+		config := fmt.Sprintf(`{"filename":%q,"daily":false,"maxsize":%d,"maxdays":0,"shared":true}`, filename, maxsize)
+		if id%2 == 1 {
+			config = strings.TrimSuffix(config, "}") + `,"asyncbuffer":true}`
+		}
+		cmd := exec.Command(os.Args[0], "-test.run=^TestSharedChild$", "-test.v")
+		cmd.Env = append(os.Environ(),
+			sharedChildEnv+"="+config,
+			fmt.Sprintf("%s_ID=%d", sharedChildEnv, id),
+			fmt.Sprintf("%s_MESSAGES=%d", sharedChildEnv, messages))
+		output := new(bytes.Buffer)
+		cmd.Stdout, cmd.Stderr = output, output
+		if !assert.NoError(t, cmd.Start()) {
+			return
+		}
+		cmds = append(cmds, cmd)
+		outputs = append(outputs, output)
+	}
+	for i, cmd := range cmds {
+		assert.NoError(t, cmd.Wait(), outputs[i].String())
+	}
+
+	w := newFileLogWriter()
+	w.Filename = filename
+	lines := readLines(t, w)
+	assert.Len(t, lines, children*messages)
+
+	// each message whole, once, in order
+	next := make([]int, children)
+	line := regexp.MustCompile(`^p(\d) (\d{5}) (x*)$`)
+	for _, l := range lines {
+		m := line.FindStringSubmatch(l)
+		if !assert.NotNil(t, m, l) {
+			continue
+		}
+		id, _ := strconv.Atoi(m[1])
+		i, _ := strconv.Atoi(m[2])
+		assert.Equal(t, next[id], i, "process %d", id)
+		assert.Len(t, m[3], 10*id)
+		next[id] = i + 1
+	}
+
+	// rotated on the size written by all of them, a buffer at most over it
+	segments, err := w.Segments()
+	assert.NoError(t, err)
+	total := 0
+	for _, l := range lines {
+		total += len(l) + 1
+	}
+	assert.True(t, len(segments) >= total/(maxsize+8*1024), "%d segments", len(segments))
+	for _, s := range segments {
+		assert.True(t, s.Size >= maxsize && s.Size < maxsize+8*1024, "%s: %d bytes", s.Path, s.Size)
+	}
+}
+
+func TestSharedConfig(t *testing.T) {
+	w := NewFileWriter()
+	err := w.Init(`{"filename":"app.log","shared":true,"direct":true}`)
+	assert.EqualError(t, err, "hooks/file: shared and direct can't both be set")
+}
@@ -0,0 +1,46 @@
+package file
+
+import (
+	"bytes"
+	"io"
+)
+
+var _ io.WriteCloser = (*FileLogWriter)(nil)
+
+// NewWriter returns a FileLogWriter configured in JSON, see ParseConfig and
+// OpenWriter.
+// It is an io.WriteCloser, so it can be the Out of a logrus.Logger or the
+// sink of any hook writing to an io.Writer, with the rotation, the retention
+// and the buffering of the configuration:
+//
+//	w, err := file.NewWriter(`{"filename":"logs/app.log","maxsize":10485760}`)
+//	if err != nil {
+//		return err
+//	}
+//	defer w.Close()
+//	log.Out = w
+//
+// Close it, or Flush it, before the program exits, including through Fatal,
+// when it is buffered.
+func NewWriter(jsonConfig string) (*FileLogWriter, error) {
+	cfg, err := ParseConfig(jsonConfig)
+	if err != nil {
+		return nil, err
+	}
+	return OpenWriter(cfg)
+}
+
+// Write writes p as is, the formatter having ended the entries with a
+// newline. The lines of p count towards Maxlines. Level doesn't filter
+// what is written here, and with AsyncBuffer, p is written within
+// FlushInterval, when the buffer is full, or on Flush or Close.
+func (w *FileLogWriter) Write(p []byte) (int, error) {
+	if w.AsyncBuffer {
+		if err := w.writeAsync(string(p), false, false); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	return w.writeSync(p, bytes.Count(p, []byte{'\n'}))
+}
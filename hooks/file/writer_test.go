@@ -0,0 +1,99 @@
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriterAsLoggerOut(t *testing.T) {
+	cases := []struct {
+		config   string
+		segments int
+	}{
+		{`,"maxlines":10,"maxdays":0`, 2},
+		// the buffer is written as a whole, then the file rotated
+		{`,"maxlines":10,"maxdays":0,"asyncbuffer":true`, 0},
+	}
+	for _, c := range cases {
+		config := c.config
+		dir, err := ioutil.TempDir("", "logrus-file")
+		assert.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		w, err := NewWriter(fmt.Sprintf(`{"filename":%q,"daily":false%s}`, filepath.Join(dir, "app.log"), config))
+		assert.NoError(t, err)
+		log := logrus.New()
+		log.Out = w
+		log.Formatter = new(logrus.JSONFormatter)
+		for i := 0; i < 25; i++ {
+			log.WithField("i", i).Info("message")
+		}
+		assert.NoError(t, w.Close())
+
+		lines := readLines(t, w)
+		if assert.Len(t, lines, 25, config) {
+			for i, line := range lines {
+				var data map[string]interface{}
+				assert.NoError(t, json.Unmarshal([]byte(line), &data), line)
+				assert.Equal(t, float64(i), data["i"])
+			}
+		}
+		segments, err := w.Segments()
+		assert.NoError(t, err)
+		assert.Len(t, segments, c.segments, config)
+	}
+}
+
+func TestWriterSharedWithHook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := newTestWriter(t, dir, `,"level":3`)
+	log := logrus.New()
+	log.Out = w
+	log.Formatter = new(logrus.JSONFormatter)
+	log.Level = logrus.InfoLevel
+	hook := NewWriterHook(w, "hook %M")
+	log.Hooks.Add(hook)
+
+	// the level of the writer only filters the hook
+	log.Info("info")
+	log.Warn("warn")
+	assert.NoError(t, hook.Close())
+
+	_, err = w.Write([]byte("after\n"))
+	assert.Equal(t, ErrClosed, err)
+
+	b, err := ioutil.ReadFile(w.Filename)
+	assert.NoError(t, err)
+	assert.Regexp(t, `^\{.*"msg":"info".*\}\nhook warn\n\{.*"msg":"warn".*\}\n$`, string(b))
+}
+
+func TestWriteKeepsBytes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrus-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, config := range []string{"", `,"asyncbuffer":true`} {
+		w := newTestWriter(t, dir, config)
+		n, err := w.Write([]byte("no newline"))
+		assert.NoError(t, err)
+		assert.Equal(t, 10, n)
+		_, err = w.Write(nil)
+		assert.NoError(t, err)
+		assert.NoError(t, w.Close())
+
+		b, err := ioutil.ReadFile(w.Filename)
+		assert.NoError(t, err)
+		assert.Equal(t, "no newline", string(b))
+		assert.NoError(t, os.Remove(w.Filename))
+	}
+}
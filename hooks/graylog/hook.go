@@ -6,7 +6,6 @@ import (
 	"os"
 	"runtime"
 	"strings"
-	"time"
 
 	"github.com/gogap/go-gelf/gelf"
 	"github.com/logrus"
@@ -101,7 +100,7 @@ func (hook *GraylogHook) fire() {
 			Host:     hook.Facility + ":" + host,
 			Short:    string(short),
 			Full:     full,
-			TimeUnix: float64(time.Now().Unix()),
+			TimeUnix: float64(entry.Time.UnixNano()) / 1e9,
 			Level:    level,
 			Facility: hook.Facility,
 			Extra:    extra,
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"net"
 	"os"
-	"time"
 
 	"github.com/logrus"
 )
@@ -29,7 +28,7 @@ func NewPapertrailHook(host string, port int, appName string) (*PapertrailHook,
 
 // Fire is called when a log event is fired.
 func (hook *PapertrailHook) Fire(entry *logrus.Entry) error {
-	date := time.Now().Format(format)
+	date := entry.Time.Format(format)
 	msg, _ := entry.String()
 	payload := fmt.Sprintf("<22> %s %s: %s", date, hook.AppName, msg)
 
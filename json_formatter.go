@@ -3,14 +3,23 @@ package logrus
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 )
 
 type JSONFormatter struct {
 	// TimestampFormat sets the format used for marshaling timestamps.
 	TimestampFormat string
+
+	// Location the timestamps are converted to, e.g. time.UTC.
+	Location *time.Location
+
+	// Sanitize, see SanitizePolicy, is off by default: JSON escapes them.
+	Sanitize SanitizePolicy
 }
 
 func (f *JSONFormatter) Format(entry *Entry) ([]byte, error) {
+	entry = SanitizeEntry(entry, f.Sanitize, SanitizeNone)
+
 	data := make(Fields, len(entry.Data)+3)
 	for k, v := range entry.Data {
 		switch v := v.(type) {
@@ -29,7 +38,7 @@ func (f *JSONFormatter) Format(entry *Entry) ([]byte, error) {
 		timestampFormat = DefaultTimestampFormat
 	}
 
-	data["time"] = entry.Time.Format(timestampFormat)
+	data["time"] = timeIn(entry.Time, f.Location).Format(timestampFormat)
 	data["msg"] = entry.Message
 	data["level"] = entry.Level.String()
 
@@ -0,0 +1,227 @@
+package logrus
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// SizeLimits bounds the size of entries before they are formatted, so that a
+// huge payload doesn't end up in full in Out, in hook buffers or in datagrams
+// of network hooks. Truncated values end with a "…(truncated N bytes)"
+// marker, N being the number of bytes cut, and are cut on UTF-8 boundaries.
+// Limits are in bytes, zero meaning no limit:
+//
+//	log.SizeLimits = &logrus.SizeLimits{MaxMessageBytes: 4 << 10, MaxFieldBytes: 1 << 10}
+//	log.Hooks.Add((&logrus.SizeLimits{MaxEntryBytes: 1400}).Hook(syslogHook))
+type SizeLimits struct {
+	// MaxFieldBytes limits every field value. Strings, errors and byte slices
+	// are truncated; maps, slices and structs are replaced with their
+	// truncated `%v` form when it is too long.
+	MaxFieldBytes int
+
+	// MaxMessageBytes limits the message.
+	MaxMessageBytes int
+
+	// MaxEntryBytes limits the message plus the keys and values of the fields,
+	// not counting what the formatter adds. The largest of them are truncated
+	// first.
+	MaxEntryBytes int
+}
+
+const truncatedMarker = "…(truncated %d bytes)"
+
+var truncatedMarkerRegexp = regexp.MustCompile(`…\(truncated (\d+) bytes\)$`)
+
+// Truncate cuts s to at most max bytes, marker included, on a UTF-8 boundary.
+// When max is too small to hold the marker, only the marker is returned. A
+// string truncated again keeps a single marker counting all the bytes cut.
+func Truncate(s string, max int) string {
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+
+	cut := 0
+	if m := truncatedMarkerRegexp.FindStringSubmatchIndex(s); m != nil {
+		cut, _ = strconv.Atoi(s[m[2]:m[3]])
+		s = s[:m[0]]
+	}
+
+	keep := max
+	for i := 0; i < 3; i++ {
+		// the marker gets shorter as more is kept, converge on its length
+		n := max - len(fmt.Sprintf(truncatedMarker, cut+len(s)-keep))
+		if n < 0 {
+			n = 0
+		}
+		if n > len(s) {
+			n = len(s)
+		}
+		if n == keep {
+			break
+		}
+		keep = n
+	}
+	for keep > 0 && keep < len(s) && !utf8.RuneStart(s[keep]) {
+		keep--
+	}
+	return s[:keep] + fmt.Sprintf(truncatedMarker, cut+len(s)-keep)
+}
+
+// LimitEntry returns entry, or a copy of it with its message and fields
+// truncated to the limits.
+func (l *SizeLimits) LimitEntry(entry *Entry) *Entry {
+	limited := *entry
+	changed := false
+
+	if l.MaxMessageBytes > 0 && len(entry.Message) > l.MaxMessageBytes {
+		limited.Message = Truncate(entry.Message, l.MaxMessageBytes)
+		changed = true
+	}
+
+	if l.MaxFieldBytes > 0 {
+		for k, v := range entry.Data {
+			if s, ok := oversizedValue(v, l.MaxFieldBytes); ok {
+				if sameFields(limited.Data, entry.Data) {
+					limited.Data = copyFields(entry.Data)
+				}
+				limited.Data[k] = Truncate(s, l.MaxFieldBytes)
+				changed = true
+			}
+		}
+	}
+
+	if l.MaxEntryBytes > 0 && l.limitTotal(&limited, entry) {
+		changed = true
+	}
+
+	if !changed {
+		return entry
+	}
+	return &limited
+}
+
+// limitTotal shrinks the largest parts of limited until the entry fits
+// MaxEntryBytes, copying the fields of the original entry before changing
+// them.
+func (l *SizeLimits) limitTotal(limited, original *Entry) bool {
+	type part struct {
+		message bool
+		key     string
+		text    string
+	}
+
+	total := len(limited.Message)
+	parts := []part{{message: true, text: limited.Message}}
+	for k, v := range limited.Data {
+		total += len(k)
+		s, isText := valueText(v)
+		if isText {
+			parts = append(parts, part{key: k, text: s})
+		}
+		total += len(s)
+	}
+	if total <= l.MaxEntryBytes {
+		return false
+	}
+
+	sort.SliceStable(parts, func(i, j int) bool {
+		if len(parts[i].text) != len(parts[j].text) {
+			return len(parts[i].text) > len(parts[j].text)
+		}
+		return parts[i].key < parts[j].key
+	})
+
+	for _, p := range parts {
+		excess := total - l.MaxEntryBytes
+		if excess <= 0 {
+			break
+		}
+		max := len(p.text) - excess
+		if max < 0 {
+			max = 0
+		}
+		truncated := Truncate(p.text, max)
+		if len(truncated) >= len(p.text) {
+			continue // too short to hold the marker
+		}
+		total -= len(p.text) - len(truncated)
+
+		if p.message {
+			limited.Message = truncated
+			continue
+		}
+		if sameFields(limited.Data, original.Data) {
+			limited.Data = copyFields(limited.Data)
+		}
+		limited.Data[p.key] = truncated
+	}
+	return true
+}
+
+// Hook wraps hook so that it sees entries within these limits, e.g. stricter
+// ones for a transport with a datagram size limit. Limits set on the Logger
+// apply first, so a hook can't be given looser limits than its logger.
+func (l *SizeLimits) Hook(hook Hook) Hook {
+	return &limitedHook{hook: hook, limits: l}
+}
+
+type limitedHook struct {
+	hook   Hook
+	limits *SizeLimits
+}
+
+func (h *limitedHook) Levels() []Level {
+	return h.hook.Levels()
+}
+
+func (h *limitedHook) Fire(entry *Entry) error {
+	return h.hook.Fire(h.limits.LimitEntry(entry))
+}
+
+// oversizedValue returns the text of v when it is longer than max.
+func oversizedValue(v interface{}, max int) (string, bool) {
+	s, isText := valueText(v)
+	return s, isText && len(s) > max
+}
+
+// valueText returns the text logged for v. isText is false for the values
+// which are only approximated by their text, e.g. numbers.
+func valueText(v interface{}) (s string, isText bool) {
+	switch v := v.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	case error:
+		return v.Error(), true
+	case fmt.Stringer:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), false
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v), false
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct, reflect.Ptr, reflect.Interface:
+		return fmt.Sprintf("%v", v), true
+	}
+	return fmt.Sprint(v), false
+}
+
+func copyFields(data Fields) Fields {
+	copied := make(Fields, len(data))
+	for k, v := range data {
+		copied[k] = v
+	}
+	return copied
+}
+
+func sameFields(a, b Fields) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
@@ -0,0 +1,100 @@
+package logrus
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", Truncate("short", 10))
+	assert.Equal(t, "short", Truncate("short", 0))
+
+	s := strings.Repeat("a", 100)
+	truncated := Truncate(s, 40)
+	assert.Equal(t, strings.Repeat("a", 17)+"…(truncated 83 bytes)", truncated)
+	assert.Len(t, truncated, 40)
+
+	// never cut a rune in half
+	s = strings.Repeat("é", 50)
+	for max := 24; max < 40; max++ {
+		truncated = Truncate(s, max)
+		assert.True(t, utf8.ValidString(truncated), truncated)
+		assert.True(t, len(truncated) <= max, truncated)
+	}
+
+	// truncating again counts all the bytes cut
+	assert.Equal(t, strings.Repeat("a", 5)+"…(truncated 95 bytes)", Truncate(Truncate(strings.Repeat("a", 100), 40), 28))
+
+	// too small for the marker
+	assert.Equal(t, "…(truncated 100 bytes)", Truncate(strings.Repeat("a", 100), 5))
+}
+
+func TestSizeLimits(t *testing.T) {
+	l := &SizeLimits{MaxMessageBytes: 30, MaxFieldBytes: 30}
+
+	entry := WithFields(Fields{
+		"body":  strings.Repeat("x", 1000),
+		"err":   errors.New(strings.Repeat("e", 100)),
+		"list":  []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+		"small": "ok",
+		"n":     123456789,
+	})
+	entry.Message = strings.Repeat("m", 100)
+
+	limited := l.LimitEntry(entry)
+	assert.Equal(t, strings.Repeat("m", 7)+"…(truncated 93 bytes)", limited.Message)
+	assert.Equal(t, strings.Repeat("x", 6)+"…(truncated 994 bytes)", limited.Data["body"])
+	assert.Equal(t, strings.Repeat("e", 7)+"…(truncated 93 bytes)", limited.Data["err"])
+	assert.Equal(t, "[1 2 3 …(truncated 30 bytes)", limited.Data["list"])
+	assert.Equal(t, "ok", limited.Data["small"])
+	assert.Equal(t, 123456789, limited.Data["n"])
+
+	// the entry itself is left alone
+	assert.Len(t, entry.Message, 100)
+	assert.Len(t, entry.Data["body"], 1000)
+
+	small := WithField("a", "b")
+	assert.True(t, small == l.LimitEntry(small))
+}
+
+func TestSizeLimitsEntry(t *testing.T) {
+	l := &SizeLimits{MaxEntryBytes: 200}
+
+	entry := WithFields(Fields{"big": strings.Repeat("x", 1000), "medium": strings.Repeat("y", 100), "n": 1})
+	entry.Message = "hello"
+
+	limited := l.LimitEntry(entry)
+	size := len(limited.Message)
+	for k, v := range limited.Data {
+		s, _ := valueText(v)
+		size += len(k) + len(s)
+	}
+	assert.True(t, size <= 200, "%d", size)
+	assert.Equal(t, "hello", limited.Message)
+	assert.Equal(t, strings.Repeat("y", 100), limited.Data["medium"])
+	assert.True(t, strings.HasPrefix(limited.Data["big"].(string), "xxx"), limited.Data["big"])
+	assert.True(t, strings.HasSuffix(limited.Data["big"].(string), " bytes)"), limited.Data["big"])
+}
+
+func TestSizeLimitsLoggerAndHook(t *testing.T) {
+	var buffer bytes.Buffer
+	hook := &redactRecorder{}
+
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &JSONFormatter{}
+	logger.SizeLimits = &SizeLimits{MaxMessageBytes: 1000}
+	logger.Hooks.Add((&SizeLimits{MaxMessageBytes: 50}).Hook(hook))
+
+	logger.Info(strings.Repeat("m", 5000))
+
+	assert.Contains(t, buffer.String(), "…(truncated 4025 bytes)")
+	assert.True(t, buffer.Len() < 1100)
+	assert.Len(t, hook.entries[0].Message, 50)
+	assert.Equal(t, strings.Repeat("m", 25)+"…(truncated 4975 bytes)", hook.entries[0].Message)
+}
@@ -8,7 +8,7 @@ package logrus
 import (
     "bytes"
     "fmt"
-    "runtime"
+    "time"
 )
 
 const LOG_TIME_FORMAT = "2006-01-02 15:04:05" // Never modify this special time format.
@@ -19,6 +19,15 @@ type LogFormatter struct {
 
     // Set to true to bypass checking for a TTY before outputting colors.
     ForceColors bool
+
+    // Theme of the colors, defaults to DefaultColorTheme.
+    Theme *ColorTheme
+
+    // Sanitize the message and fields, escaped by default, see SanitizePolicy.
+    Sanitize SanitizePolicy
+
+    // Location of %D and %T.
+    Location *time.Location
 }
 
 // Format as:
@@ -40,36 +49,31 @@ func (f *LogFormatter) Format(entry *Entry) ([]byte, error) {
         f.PrintFormat = "[%T %s] [%L] %M"
     }
 
-    isColorTerminal := isTerminal && (runtime.GOOS != "windows")
-    isColored := (f.ForceColors && isColorTerminal)
-
-    var levelColor int
-    switch entry.Level {
-    case DebugLevel:
-        levelColor = black
-    case InfoLevel:
-        levelColor = green
-    case WarnLevel:
-        levelColor = yellow
-    case ErrorLevel, FatalLevel, PanicLevel:
-        levelColor = red
-    default:
-        levelColor = nocolor
+    // Colors are only used when forced and the output is a terminal.
+    colorMode := ColorsOff
+    if out := entry.output(); f.ForceColors && isTerminalWriter(out) {
+        colorMode = ColorModeFor(out, false, false)
     }
-    record := Prepare(entry.Level, entry.Message, entry.Logger.PkgPath)
-    des := NewPrintFormat(f.PrintFormat).Format(record)
+    theme := themeOrDefault(f.Theme)
+    sanitize := f.Sanitize.Resolve(SanitizeEscape)
+
+    record := PrepareEntry(entry)
+    printFormat := NewPrintFormat(f.PrintFormat)
+    printFormat.Sanitize = sanitize
+    printFormat.Location = f.Location
+    des := printFormat.Format(record)
     //fmt.Printf("entry:%v record:%v des:%s printFormat:<%s>\n", entry, record, des, f.PrintFormat)
-    if isColored {
-        fmt.Fprintf(bfr, "\x1b[%dm%s\x1b[0m", levelColor, des)
+    if colorMode != ColorsOff {
+        bfr.WriteString(theme.Level(entry.Level).Sprint(colorMode, des))
         for _, k := range keys {
-            v := entry.Data[k]
-            fmt.Fprintf(bfr, " \x1b[%dm%s\x1b[0m=%v", levelColor, k, v)
+            v := sanitize.SanitizeField(fmt.Sprint(entry.Data[k]))
+            fmt.Fprintf(bfr, " %s=%s", theme.Key(k, entry.Level).Sprint(colorMode, sanitize.SanitizeField(k)), v)
         }
     } else {
         bfr.WriteString(des)
         for _, k := range keys {
-            v := entry.Data[k]
-            fmt.Fprintf(bfr, "%v=%v", k, v)
+            v := sanitize.SanitizeField(fmt.Sprint(entry.Data[k]))
+            fmt.Fprintf(bfr, " %s=%s", sanitize.SanitizeField(k), v)
         }
     }
     if len(keys) > 0 {
@@ -4,6 +4,7 @@ import (
 	"io"
 	"os"
 	"sync"
+	"time"
 )
 
 type Logger struct {
@@ -26,8 +27,25 @@ type Logger struct {
 	// to) `logrus.Info`, which allows Info(), Warn(), Error() and Fatal() to be
 	// logged. `logrus.Debug` is useful in
 	Level Level
+	// Redactor, when set, removes sensitive data from the entries before the
+	// hooks and the formatter see them.
+	Redactor *Redactor
+	// Pseudonymizer, when set, replaces identifiers with pseudonyms in the
+	// entries before the hooks and the formatter see them.
+	Pseudonymizer *Pseudonymizer
+	// SizeLimits, when set, truncates the entries before the hooks and the
+	// formatter see them.
+	SizeLimits *SizeLimits
+	// Sequencer, when set, numbers the entries so that lost or reordered
+	// lines can be detected downstream.
+	Sequencer *Sequencer
+	// Clock gives the time of the entries, defaults to time.Now. Set it to a
+	// fixed time in tests for deterministic output.
+	Clock func() time.Time
 	// Used to sync writing to the log.(used by entry.go)
 	mu sync.Mutex
+	// Name of the logger, available to formatters such as TemplateFormatter.
+	Name string
 	// Add by 鬼股神生; <在确定日志所属文件名时用于做定位依据;>
 	PkgPath string // e.g: "log/log.go" => log包是我项目当中新创建的包,log.go封装了内部Logger对象,这样项目其他地方直接用包名调用函数即可实际记录日志;
 }
@@ -74,6 +92,14 @@ func NewLogger(out io.Writer, formatter Formatter, level Level, pkgPath string)
 	return log
 }
 
+// now returns the current time according to the Clock of the logger.
+func (logger *Logger) now() time.Time {
+	if logger.Clock != nil {
+		return logger.Clock()
+	}
+	return time.Now()
+}
+
 // Adds a field to the log entry, note that you it doesn't log until you call
 // Debug, Print, Info, Warn, Fatal or Panic. It only creates a log entry.
 // Ff you want multiple fields, use `WithFields`.
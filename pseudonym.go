@@ -0,0 +1,168 @@
+package logrus
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// MinPseudonymKeySize is the minimum size of the keys of a Pseudonymizer.
+const MinPseudonymKeySize = 16
+
+// Pseudonym returns the pseudonym of value under key: the first 20 hex
+// digits of its HMAC-SHA256. It is what a Pseudonymizer logs, so that a
+// known value can be looked up in the logs, see cmd/pseudonym.
+func Pseudonym(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)[:10])
+}
+
+// TruncateIP keeps the first bits of an IP address, e.g. "203.0.113.7"
+// becomes "203.0.113.0/24" with 24 bits. A port, as in "203.0.113.7:443", is
+// dropped. ok is false when s isn't an IP address.
+func TruncateIP(s string, ipv4Bits, ipv6Bits int) (prefix string, ok bool) {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		host, _, err := net.SplitHostPort(s)
+		if err != nil {
+			return "", false
+		}
+		if ip = net.ParseIP(host); ip == nil {
+			return "", false
+		}
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return fmt.Sprintf("%s/%d", ip4.Mask(net.CIDRMask(ipv4Bits, 32)), ipv4Bits), true
+	}
+	return fmt.Sprintf("%s/%d", ip.Mask(net.CIDRMask(ipv6Bits, 128)), ipv6Bits), true
+}
+
+// Pseudonymizer replaces identifiers such as user ids and emails with keyed
+// pseudonyms, so that the lines of a user can be correlated without logging
+// who the user is, and truncates IP addresses:
+//
+//	p, err := logrus.NewPseudonymizer("2017-07", key)
+//	p.Fields = []string{"user_id", "email"}
+//	p.IPFields = []string{"remote_addr"}
+//	log.Pseudonymizer = p
+//
+// Fields are matched like the Keys of a Redactor, including dotted paths into
+// nested maps and structs. The id of the key is logged with the entries in
+// KeyIDField, so that pseudonyms can be re-derived after keys are rotated.
+// Set the Pseudonymizer on a Logger to apply it to every entry, or wrap
+// individual hooks with Hook.
+type Pseudonymizer struct {
+	// Fields replaced with their pseudonym.
+	Fields []string
+
+	// IPFields truncated to IPv4Prefix or IPv6Prefix bits, defaulting to 24
+	// and 48. Values which aren't IP addresses are pseudonymized.
+	IPFields   []string
+	IPv4Prefix int
+	IPv6Prefix int
+
+	// KeyIDField holds the id of the key, defaults to "pseudonym_key".
+	KeyIDField string
+
+	mu    sync.RWMutex
+	keyID string
+	key   []byte
+}
+
+// NewPseudonymizer creates a Pseudonymizer using key, identified by keyID.
+func NewPseudonymizer(keyID string, key []byte) (*Pseudonymizer, error) {
+	p := &Pseudonymizer{}
+	if err := p.Rotate(keyID, key); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Rotate switches to a new key. It is safe to call while logging.
+func (p *Pseudonymizer) Rotate(keyID string, key []byte) error {
+	if len(key) < MinPseudonymKeySize {
+		return fmt.Errorf("logrus: pseudonym key must be at least %d bytes", MinPseudonymKeySize)
+	}
+	if keyID == "" {
+		return fmt.Errorf("logrus: pseudonym key id is empty")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keyID = keyID
+	p.key = append([]byte(nil), key...)
+	return nil
+}
+
+// PseudonymizeEntry returns a copy of entry with the configured fields
+// replaced. Without a key, their values are replaced with "***".
+func (p *Pseudonymizer) PseudonymizeEntry(entry *Entry) *Entry {
+	p.mu.RLock()
+	keyID, key := p.keyID, p.key
+	p.mu.RUnlock()
+
+	replaced := false
+	pseudonym := func(value string) string {
+		replaced = true
+		if key == nil {
+			return "***"
+		}
+		return Pseudonym(key, value)
+	}
+
+	ipv4Bits, ipv6Bits := p.IPv4Prefix, p.IPv6Prefix
+	if ipv4Bits == 0 {
+		ipv4Bits = 24
+	}
+	if ipv6Bits == 0 {
+		ipv6Bits = 48
+	}
+	truncate := func(value string) string {
+		if prefix, ok := TruncateIP(value, ipv4Bits, ipv6Bits); ok {
+			return prefix
+		}
+		return pseudonym(value)
+	}
+
+	data := entry.Data
+	if len(p.Fields) > 0 {
+		data = (&Redactor{Keys: p.Fields, Replace: pseudonym}).RedactFields(data)
+	}
+	if len(p.IPFields) > 0 {
+		data = (&Redactor{Keys: p.IPFields, Replace: truncate}).RedactFields(data)
+	}
+	if replaced && key != nil {
+		keyIDField := p.KeyIDField
+		if keyIDField == "" {
+			keyIDField = "pseudonym_key"
+		}
+		data[keyIDField] = keyID
+	}
+
+	pseudonymized := *entry
+	pseudonymized.Data = data
+	return &pseudonymized
+}
+
+// Hook wraps hook so that it only sees pseudonymized entries, for hooks
+// shipping logs to places the identifiers mustn't reach.
+func (p *Pseudonymizer) Hook(hook Hook) Hook {
+	return &pseudonymizedHook{hook: hook, p: p}
+}
+
+type pseudonymizedHook struct {
+	hook Hook
+	p    *Pseudonymizer
+}
+
+func (h *pseudonymizedHook) Levels() []Level {
+	return h.hook.Levels()
+}
+
+func (h *pseudonymizedHook) Fire(entry *Entry) error {
+	return h.hook.Fire(h.p.PseudonymizeEntry(entry))
+}
@@ -0,0 +1,105 @@
+package logrus
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var pseudonymKey = []byte("0123456789abcdef")
+
+func TestPseudonym(t *testing.T) {
+	p := Pseudonym(pseudonymKey, "walrus@example.com")
+	assert.Len(t, p, 20)
+	assert.Equal(t, p, Pseudonym(pseudonymKey, "walrus@example.com"))
+	assert.NotEqual(t, p, Pseudonym(pseudonymKey, "seal@example.com"))
+	assert.NotEqual(t, p, Pseudonym([]byte("fedcba9876543210"), "walrus@example.com"))
+}
+
+func TestTruncateIP(t *testing.T) {
+	cases := []struct{ in, out string }{
+		{"203.0.113.7", "203.0.113.0/24"},
+		{"203.0.113.7:443", "203.0.113.0/24"},
+		{"2001:db8:85a3::8a2e:370:7334", "2001:db8:85a3::/48"},
+		{"[2001:db8::1]:443", "2001:db8::/48"},
+	}
+	for _, c := range cases {
+		prefix, ok := TruncateIP(c.in, 24, 48)
+		assert.True(t, ok, c.in)
+		assert.Equal(t, c.out, prefix)
+	}
+
+	_, ok := TruncateIP("localhost", 24, 48)
+	assert.False(t, ok)
+}
+
+func TestPseudonymizer(t *testing.T) {
+	_, err := NewPseudonymizer("k1", []byte("short"))
+	assert.Error(t, err)
+
+	p, err := NewPseudonymizer("k1", pseudonymKey)
+	assert.NoError(t, err)
+	p.Fields = []string{"user_id", "User.Email"}
+	p.IPFields = []string{"ip", "remote"}
+
+	entry := WithFields(Fields{
+		"user_id": 42,
+		"user":    map[string]interface{}{"email": "walrus@example.com", "plan": "pro"},
+		"ip":      net.ParseIP("203.0.113.7"),
+		"remote":  "not an ip",
+		"path":    "/login",
+	})
+	pseudonymized := p.PseudonymizeEntry(entry)
+
+	assert.Equal(t, Pseudonym(pseudonymKey, "42"), pseudonymized.Data["user_id"])
+	assert.Equal(t, map[string]interface{}{"email": Pseudonym(pseudonymKey, "walrus@example.com"), "plan": "pro"}, pseudonymized.Data["user"])
+	assert.Equal(t, "203.0.113.0/24", pseudonymized.Data["ip"])
+	assert.Equal(t, Pseudonym(pseudonymKey, "not an ip"), pseudonymized.Data["remote"])
+	assert.Equal(t, "/login", pseudonymized.Data["path"])
+	assert.Equal(t, "k1", pseudonymized.Data["pseudonym_key"])
+	assert.Equal(t, 42, entry.Data["user_id"])
+
+	// rotation
+	key2 := []byte("fedcba9876543210")
+	assert.NoError(t, p.Rotate("k2", key2))
+	pseudonymized = p.PseudonymizeEntry(entry)
+	assert.Equal(t, Pseudonym(key2, "42"), pseudonymized.Data["user_id"])
+	assert.Equal(t, "k2", pseudonymized.Data["pseudonym_key"])
+
+	// nothing to replace, no key id
+	pseudonymized = p.PseudonymizeEntry(WithField("path", "/"))
+	assert.Equal(t, Fields{"path": "/"}, pseudonymized.Data)
+
+	// without a key
+	p = &Pseudonymizer{Fields: []string{"user_id"}}
+	pseudonymized = p.PseudonymizeEntry(entry)
+	assert.Equal(t, "***", pseudonymized.Data["user_id"])
+	assert.NotContains(t, pseudonymized.Data, "pseudonym_key")
+}
+
+func TestPseudonymizerLoggerAndHook(t *testing.T) {
+	p, err := NewPseudonymizer("k1", pseudonymKey)
+	assert.NoError(t, err)
+	p.Fields = []string{"email"}
+
+	// per hook: the hook sees pseudonyms, Out the raw value
+	var buffer bytes.Buffer
+	hook := &redactRecorder{}
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &JSONFormatter{}
+	logger.Hooks.Add(p.Hook(hook))
+
+	logger.WithField("email", "walrus@example.com").Info("login")
+	assert.Contains(t, buffer.String(), "walrus@example.com")
+	assert.Equal(t, Pseudonym(pseudonymKey, "walrus@example.com"), hook.entries[0].Data["email"])
+
+	// logger-wide
+	buffer.Reset()
+	logger.Pseudonymizer = p
+	logger.WithField("email", "walrus@example.com").Info("login")
+	assert.NotContains(t, buffer.String(), "walrus@example.com")
+	assert.Contains(t, buffer.String(), `"pseudonym_key":"k1"`)
+}
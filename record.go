@@ -4,6 +4,7 @@ import (
     "bytes"
     "fmt"
     "runtime"
+    "sort"
     "strings"
     "time"
     "regexp"
@@ -14,15 +15,40 @@ const (
     FileDelimiterLogger = "logrus/logger.go"
 )
 
+// Prepare creates the record of a message logged now.
+//
+// Deprecated: use PrepareEntry, which uses the time of the entry rather than
+// a slightly different one.
 func Prepare(level Level, msg, pkgPath string) *LogRecord {
+    return prepare(level, msg, pkgPath, time.Now())
+}
+
+// PrepareEntry creates the record of entry, with the caller of the logging
+// call as source.
+func PrepareEntry(entry *Entry) *LogRecord {
+    pkgPath := ""
+    if entry.Logger != nil {
+        pkgPath = entry.Logger.PkgPath
+    }
+    rec := prepare(entry.Level, entry.Message, pkgPath, entry.Time)
+    rec.Data = entry.Data
+    return rec
+}
+
+func prepare(level Level, msg, pkgPath string, t time.Time) *LogRecord {
     depth, offset := 0, 0
     dest  := FileDelimiterLogger
 
     if pkgPath != "" {
         dest = pkgPath
     }
+    found := false
     for i, j := 0, 0; ; i++ {
-        _, file, _, _ := runtime.Caller(i)
+        _, file, _, ok := runtime.Caller(i)
+        if !ok {
+            // not logged through logger.go, e.g. with Entry.Info
+            break
+        }
         if strings.Contains(file, FileDelimiterRecord) {
             j += 1
             if j == 2 {
@@ -34,22 +60,32 @@ func Prepare(level Level, msg, pkgPath string) *LogRecord {
         if strings.Contains(file, dest) {
             // i+1: 代表下一个为目标文件名及行号; -offset: 获取真正的文件嵌套深度;
             depth = i + 1 - offset
+            found = true
             break
         }
     }
 
     funcPath, packagePath := "_", "_"
-    pc, file, line, _ := runtime.Caller(depth)
-
-    me := runtime.FuncForPC(pc)
-    if me != nil {
-        funcPath = me.Name()
-        packagePath = splitPackage(funcPath)
+    var file string
+    var line int
+    if found {
+        var pc uintptr
+        pc, file, line, _ = runtime.Caller(depth)
+        if me := runtime.FuncForPC(pc); me != nil {
+            funcPath = me.Name()
+            packagePath = splitPackage(funcPath)
+        }
+    } else if frame, ok := getCallerFrame(); ok {
+        file, line = frame.File, frame.Line
+        if frame.Function != "" {
+            funcPath = frame.Function
+            packagePath = splitPackage(funcPath)
+        }
     }
 
     return &LogRecord{
         Level:       level,
-        Timestamp:   time.Now(),
+        Timestamp:   t,
         SourceFile:  file,
         SourceLine:  line,
         Message:     msg,
@@ -74,6 +110,7 @@ type LogRecord struct {
     Message     string
     FuncPath    string
     PackagePath string
+    Data        Fields // of the entry, see PrepareEntry
 }
 
 // Format a log message before writing
@@ -84,6 +121,14 @@ type PrintFormatter interface {
 var prefixRegexp = regexp.MustCompile(`^[\-+]?[0-9]+`)
 
 type PrintFormat struct {
+    // Sanitize control characters in the message (%M) and the fields (%F),
+    // escaping them by default.
+    Sanitize SanitizePolicy
+
+    // Location of the time and date, defaults to the location of the record
+    // timestamp.
+    Location *time.Location
+
     format        string
     formatCompile string
     formatDynamic []byte
@@ -98,7 +143,8 @@ type PrintFormat struct {
 //   %S - Source: full runtime.Caller line
 //   %s - Short Source: just file and line number
 //   %x - Extra Short Source: just file without .go suffix
-//   %M - Message
+//   %M - Message, sanitized as set by PrintFormat.Sanitize
+//   %F - Fields: key=value pairs sorted by key, as written by TextFormatter
 //   %% - Percent sign
 // 	 %P - Caller Path: package path + calling function name
 // 	 %p - Caller Path: package path
@@ -210,6 +256,14 @@ func (pf *PrintFormat) compileForLevel(level int) []byte {
             sprintfFmt = append(sprintfFmt, 's')
             sprintfFmt = append(sprintfFmt, fmt_str[1:]...)
             pf.formatDynamic = append(pf.formatDynamic, 'M')
+        case 'F':
+            sprintfFmt = append(sprintfFmt, '%')
+            if num != nil {
+                sprintfFmt = append(sprintfFmt, num...)
+            }
+            sprintfFmt = append(sprintfFmt, 's')
+            sprintfFmt = append(sprintfFmt, fmt_str[1:]...)
+            pf.formatDynamic = append(pf.formatDynamic, 'F')
         case '%':
             sprintfFmt = append(sprintfFmt, '%')
             sprintfFmt = append(sprintfFmt, fmt_str...)
@@ -247,7 +301,7 @@ func (pf *PrintFormat) Format(rec *LogRecord) string {
 }
 
 func (pf *PrintFormat) getDynamic(rec *LogRecord) []interface{} {
-    tm := rec.Timestamp
+    tm := timeIn(rec.Timestamp, pf.Location)
     ret := make([]interface{}, 0, 10)
     for _, dyn := range pf.formatDynamic {
         switch dyn {
@@ -268,7 +322,9 @@ func (pf *PrintFormat) getDynamic(rec *LogRecord) []interface{} {
         case 'x':
             ret = append(ret, parseSourceXShort(rec.SourceFile))
         case 'M':
-            ret = append(ret, rec.Message)
+            ret = append(ret, pf.Sanitize.Resolve(SanitizeEscape).SanitizeMessage(rec.Message))
+        case 'F':
+            ret = append(ret, parseFields(rec.Data, pf.Sanitize.Resolve(SanitizeEscape)))
         case 'P':
             ret = append(ret, rec.FuncPath)
         case 'p':
@@ -278,6 +334,21 @@ func (pf *PrintFormat) getDynamic(rec *LogRecord) []interface{} {
     return ret
 }
 
+func parseFields(data Fields, sanitize SanitizePolicy) string {
+    keys := make([]string, 0, len(data))
+    for k := range data {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+
+    b := &bytes.Buffer{}
+    f := &TextFormatter{Sanitize: sanitize}
+    for _, k := range keys {
+        f.appendKeyValue(b, sanitize.SanitizeField(k), data[k], sanitize.Fields)
+    }
+    return strings.TrimSuffix(b.String(), " ")
+}
+
 func parseSourceLong(file string, line int) string {
     return fmt.Sprintf("%s:%d", file, line)
 }
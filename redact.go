@@ -0,0 +1,401 @@
+package logrus
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Nested values deeper than this are not redacted.
+const redactMaxDepth = 8
+
+// RedactFunc returns what to log instead of a sensitive value.
+type RedactFunc func(value string) string
+
+// RedactMask replaces values with mask, e.g. "***".
+func RedactMask(mask string) RedactFunc {
+	return func(string) string {
+		return mask
+	}
+}
+
+// RedactHash replaces values with the first 12 hex digits of their SHA-256,
+// so that equal values can still be correlated. Short or guessable values
+// such as PINs can be recovered by brute force; use a keyed hash for these.
+func RedactHash() RedactFunc {
+	return func(value string) string {
+		sum := sha256.Sum256([]byte(value))
+		return "sha256:" + hex.EncodeToString(sum[:6])
+	}
+}
+
+// RedactPartial masks the letters and digits of values with '*' except for
+// their first prefix and last suffix characters, e.g. RedactPartial(0, 4)
+// logs a card number as "**** **** **** 1111". Separators are kept. Values
+// too short to hide anything are fully masked.
+func RedactPartial(prefix, suffix int) RedactFunc {
+	return func(value string) string {
+		n := utf8.RuneCountInString(value)
+		if n <= prefix+suffix {
+			return strings.Repeat("*", n)
+		}
+		runes := []rune(value)
+		for i := prefix; i < n-suffix; i++ {
+			if unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) {
+				runes[i] = '*'
+			}
+		}
+		return string(runes)
+	}
+}
+
+// RedactPattern scrubs the matches of a regular expression in messages and
+// string values. When Regexp has a subexpression, only the text matched by
+// the first one is replaced, e.g. the token but not the "Bearer " before it.
+type RedactPattern struct {
+	Regexp *regexp.Regexp
+
+	// Replace defaults to the Replace of the Redactor.
+	Replace RedactFunc
+
+	// Valid, when set, filters out false positives among the matches.
+	Valid func(match string) bool
+}
+
+// Stock patterns for Redactor.Patterns.
+var (
+	// PANPattern matches payment card numbers passing the Luhn check and
+	// keeps their last four digits.
+	PANPattern = RedactPattern{
+		Regexp:  regexp.MustCompile(`\b\d(?:[ -]?\d){11,18}\b`),
+		Replace: RedactPartial(0, 4),
+		Valid:   luhnValid,
+	}
+
+	// JWTPattern matches JSON Web Tokens.
+	JWTPattern = RedactPattern{
+		Regexp: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+	}
+
+	// BearerPattern matches the token of "Bearer <token>" authorizations.
+	BearerPattern = RedactPattern{
+		Regexp: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`),
+	}
+)
+
+// Redactor removes sensitive data from entries before the formatter and the
+// hooks of a logger see them, see Logger.Redactor:
+//
+//	log.Redactor = &logrus.Redactor{
+//		Keys:     []string{"password", "request.headers.authorization"},
+//		Patterns: []logrus.RedactPattern{logrus.PANPattern, logrus.BearerPattern},
+//	}
+//
+// Nested maps, structs, pointers and slices in the fields are walked and
+// copied where something is redacted, the logged values are never modified.
+// A Redactor must not be changed once in use.
+type Redactor struct {
+	// Keys of the fields to redact, compared case-insensitively. A key
+	// matches at any depth, a dotted path such as "request.headers.cookie"
+	// only from the top-level fields. Struct fields are named by their JSON
+	// name if they have one, else by their Go name.
+	Keys []string
+
+	// Patterns scrubbed from the message and the string values.
+	Patterns []RedactPattern
+
+	// Replace defaults to RedactMask("***").
+	Replace RedactFunc
+
+	once sync.Once
+	keys map[string]bool
+}
+
+func (r *Redactor) init() {
+	r.once.Do(func() {
+		r.keys = make(map[string]bool, len(r.Keys))
+		for _, key := range r.Keys {
+			r.keys[strings.ToLower(key)] = true
+		}
+		if r.Replace == nil {
+			r.Replace = RedactMask("***")
+		}
+	})
+}
+
+// RedactEntry returns a copy of entry with its message and fields redacted.
+func (r *Redactor) RedactEntry(entry *Entry) *Entry {
+	redacted := *entry
+	redacted.Message = r.RedactString(entry.Message)
+	redacted.Data = r.RedactFields(entry.Data)
+	return &redacted
+}
+
+// RedactFields returns a copy of data with the sensitive values redacted.
+func (r *Redactor) RedactFields(data Fields) Fields {
+	r.init()
+	redacted := make(Fields, len(data))
+	for k, v := range data {
+		if r.denied(k, k) {
+			redacted[k] = r.Replace(fmt.Sprint(v))
+			continue
+		}
+		if rv, changed := r.redactValue(k, reflect.ValueOf(v), 0); changed {
+			redacted[k] = rv.Interface()
+		} else {
+			redacted[k] = v
+		}
+	}
+	return redacted
+}
+
+// RedactString scrubs the patterns from s.
+func (r *Redactor) RedactString(s string) string {
+	r.init()
+	for _, p := range r.Patterns {
+		replace := p.Replace
+		if replace == nil {
+			replace = r.Replace
+		}
+		s = redactPattern(s, p, replace)
+	}
+	return s
+}
+
+func redactPattern(s string, p RedactPattern, replace RedactFunc) string {
+	matches := p.Regexp.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+
+	b := make([]byte, 0, len(s))
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		if p.Valid != nil && !p.Valid(s[start:end]) {
+			continue
+		}
+		b = append(b, s[last:start]...)
+		b = append(b, replace(s[start:end])...)
+		last = end
+	}
+	return string(append(b, s[last:]...))
+}
+
+func (r *Redactor) denied(path, key string) bool {
+	return r.keys[strings.ToLower(key)] || r.keys[strings.ToLower(path)]
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// redactValue returns a redacted copy of v and true, or v and false when
+// there is nothing to redact in it.
+func (r *Redactor) redactValue(path string, v reflect.Value, depth int) (reflect.Value, bool) {
+	if !v.IsValid() || depth > redactMaxDepth {
+		return v, false
+	}
+	if v.Type().Implements(errorType) && v.CanInterface() {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return v, false
+		}
+		msg := v.Interface().(error).Error()
+		if redacted := r.RedactString(msg); redacted != msg {
+			return reflect.ValueOf(redacted), true
+		}
+		return v, false
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if redacted := r.RedactString(v.String()); redacted != v.String() {
+			return reflect.ValueOf(redacted).Convert(v.Type()), true
+		}
+	case reflect.Interface:
+		if !v.IsNil() {
+			if rv, changed := r.redactValue(path, v.Elem(), depth); changed {
+				return rv, true
+			}
+		}
+	case reflect.Ptr:
+		if !v.IsNil() {
+			if rv, changed := r.redactValue(path, v.Elem(), depth); changed {
+				if rv.Type() != v.Elem().Type() {
+					return rv, true
+				}
+				p := reflect.New(rv.Type())
+				p.Elem().Set(rv)
+				return p, true
+			}
+		}
+	case reflect.Map:
+		return r.redactMap(path, v, depth)
+	case reflect.Struct:
+		return r.redactStruct(path, v, depth)
+	case reflect.Slice, reflect.Array:
+		return r.redactSlice(path, v, depth)
+	}
+	return v, false
+}
+
+func (r *Redactor) redactMap(path string, v reflect.Value, depth int) (reflect.Value, bool) {
+	if v.Type().Key().Kind() != reflect.String || v.Len() == 0 {
+		return v, false
+	}
+
+	var redacted reflect.Value
+	for _, k := range v.MapKeys() {
+		key := k.String()
+		elem := v.MapIndex(k)
+		var rv reflect.Value
+		if r.denied(path+"."+key, key) {
+			rv = r.replacement(v.Type().Elem(), elem)
+		} else {
+			var changed bool
+			if rv, changed = r.redactValue(path+"."+key, elem, depth+1); !changed {
+				continue
+			}
+			rv = assignable(rv, v.Type().Elem())
+		}
+		if !redacted.IsValid() {
+			redacted = reflect.MakeMapWithSize(v.Type(), v.Len())
+			for _, k := range v.MapKeys() {
+				redacted.SetMapIndex(k, v.MapIndex(k))
+			}
+		}
+		redacted.SetMapIndex(k, rv)
+	}
+	if !redacted.IsValid() {
+		return v, false
+	}
+	return redacted, true
+}
+
+func (r *Redactor) redactStruct(path string, v reflect.Value, depth int) (reflect.Value, bool) {
+	var redacted reflect.Value
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue // unexported, can't be set
+		}
+		name := field.Name
+		if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag != "" && tag != "-" {
+			name = tag
+		}
+
+		var rv reflect.Value
+		if field.Tag.Get("log") == "redact" || r.denied(path+"."+name, name) || r.denied(path+"."+field.Name, field.Name) {
+			rv = r.replacement(field.Type, v.Field(i))
+		} else {
+			var changed bool
+			if rv, changed = r.redactValue(path+"."+name, v.Field(i), depth+1); !changed {
+				continue
+			}
+			rv = assignable(rv, field.Type)
+		}
+		if !redacted.IsValid() {
+			redacted = reflect.New(t).Elem()
+			redacted.Set(v)
+		}
+		redacted.Field(i).Set(rv)
+	}
+	if !redacted.IsValid() {
+		return v, false
+	}
+	return redacted, true
+}
+
+func (r *Redactor) redactSlice(path string, v reflect.Value, depth int) (reflect.Value, bool) {
+	if v.Type().Elem().Kind() == reflect.Uint8 {
+		return v, false // []byte
+	}
+
+	var redacted reflect.Value
+	for i := 0; i < v.Len(); i++ {
+		rv, changed := r.redactValue(path, v.Index(i), depth+1)
+		if !changed {
+			continue
+		}
+		if !redacted.IsValid() {
+			if v.Kind() == reflect.Slice {
+				redacted = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+				reflect.Copy(redacted, v)
+			} else {
+				redacted = reflect.New(v.Type()).Elem()
+				redacted.Set(v)
+			}
+		}
+		redacted.Index(i).Set(assignable(rv, v.Type().Elem()))
+	}
+	if !redacted.IsValid() {
+		return v, false
+	}
+	return redacted, true
+}
+
+// replacement returns the redacted form of v for a value of type t: the
+// replacement string where t can hold it, else the zero value of t.
+func (r *Redactor) replacement(t reflect.Type, v reflect.Value) reflect.Value {
+	var s string
+	if v.IsValid() && v.CanInterface() {
+		s = fmt.Sprint(v.Interface())
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String && v.Len() == 1 {
+			s = v.Index(0).String() // e.g. a header
+		}
+	}
+	s = r.Replace(s)
+
+	switch {
+	case t.Kind() == reflect.String:
+		return reflect.ValueOf(s).Convert(t)
+	case t.Kind() == reflect.Interface && reflect.TypeOf(s).Implements(t):
+		return reflect.ValueOf(s)
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String:
+		rv := reflect.MakeSlice(t, 1, 1)
+		rv.Index(0).SetString(s)
+		return rv
+	}
+	return reflect.Zero(t)
+}
+
+// assignable adapts a redacted value to be stored as a t. Errors and
+// Stringers are redacted to strings, which only fit interface types.
+func assignable(v reflect.Value, t reflect.Type) reflect.Value {
+	if v.Type().AssignableTo(t) {
+		return v
+	}
+	if v.Kind() == reflect.String && t.Kind() == reflect.String {
+		return v.Convert(t)
+	}
+	return reflect.Zero(t)
+}
+
+// luhnValid tells whether the digits of s pass the Luhn check.
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 12 && sum%10 == 0
+}
@@ -0,0 +1,176 @@
+package logrus
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// SanitizeMode is what a formatter does with control characters in the
+// messages and fields it writes, which would otherwise let user input forge
+// log lines (CR, LF) or take over the terminal (ANSI escape sequences).
+type SanitizeMode uint8
+
+const (
+	// SanitizeDefault is the default of the formatter: SanitizeEscape for the
+	// text formats and SanitizeNone for JSON and the binary formats, which
+	// already encode control characters safely.
+	SanitizeDefault SanitizeMode = iota
+	// SanitizeEscape writes control characters as Go escapes: \n, \x1b, ...
+	SanitizeEscape
+	// SanitizeReplace writes SanitizePolicy.Replacement instead of them.
+	SanitizeReplace
+	// SanitizeStrip removes them.
+	SanitizeStrip
+	// SanitizeNone writes them untouched.
+	SanitizeNone
+)
+
+// SanitizePolicy configures the sanitisation of a formatter, with separate
+// rules for the message and the fields (both keys and values).
+type SanitizePolicy struct {
+	Message SanitizeMode
+	Fields  SanitizeMode
+
+	// Replacement of control characters with SanitizeReplace, defaults to "?".
+	Replacement string
+}
+
+// Resolve returns the policy with the SanitizeDefault modes replaced by def.
+func (p SanitizePolicy) Resolve(def SanitizeMode) SanitizePolicy {
+	if p.Message == SanitizeDefault {
+		p.Message = def
+	}
+	if p.Fields == SanitizeDefault {
+		p.Fields = def
+	}
+	return p
+}
+
+// SanitizeMessage applies the message rule to s.
+func (p SanitizePolicy) SanitizeMessage(s string) string {
+	return Sanitize(s, p.Message, p.Replacement)
+}
+
+// SanitizeField applies the field rule to s.
+func (p SanitizePolicy) SanitizeField(s string) string {
+	return Sanitize(s, p.Fields, p.Replacement)
+}
+
+// Sanitize applies mode to the control characters of s: C0 controls except
+// tab, DEL, C1 controls, the Unicode line and paragraph separators, the
+// bidirectional overrides and invalid UTF-8. SanitizeDefault escapes.
+func Sanitize(s string, mode SanitizeMode, replacement string) string {
+	if mode == SanitizeNone || isSanitized(s) {
+		return s
+	}
+	if replacement == "" {
+		replacement = "?"
+	}
+
+	b := bytes.NewBuffer(make([]byte, 0, len(s)+8))
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if !isUnsafeRune(r, size) {
+			b.WriteString(s[i : i+size])
+			i += size
+			continue
+		}
+		switch mode {
+		case SanitizeReplace:
+			b.WriteString(replacement)
+		case SanitizeStrip:
+		default:
+			b.WriteString(escapeRune(s[i:i+size], r))
+		}
+		i += size
+	}
+	return b.String()
+}
+
+func isSanitized(s string) bool {
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if isUnsafeRune(r, size) {
+			return false
+		}
+		i += size
+	}
+	return true
+}
+
+func isUnsafeRune(r rune, size int) bool {
+	switch {
+	case r == utf8.RuneError && size == 1:
+		return true
+	case r == '\t':
+		return false
+	case r < 0x20, r >= 0x7f && r < 0xa0:
+		return true
+	case r == 0x2028, r == 0x2029:
+		return true
+	case r >= 0x202a && r <= 0x202e, r >= 0x2066 && r <= 0x2069:
+		return true
+	}
+	return false
+}
+
+func escapeRune(s string, r rune) string {
+	switch {
+	case r == '\n':
+		return `\n`
+	case r == '\r':
+		return `\r`
+	case r == utf8.RuneError:
+		return fmt.Sprintf(`\x%02x`, s[0])
+	case r < 0x80:
+		return fmt.Sprintf(`\x%02x`, r)
+	}
+	q := strconv.QuoteRuneToASCII(r)
+	return q[1 : len(q)-1]
+}
+
+// SanitizeFields returns a copy of data with the field rule of p applied to
+// the keys, and to the values that are strings, errors or nested Fields.
+// Other values are kept as they are.
+func SanitizeFields(data Fields, p SanitizePolicy) Fields {
+	sanitized := make(Fields, len(data))
+	for k, v := range data {
+		sanitized[p.SanitizeField(k)] = sanitizeValue(v, p)
+	}
+	return sanitized
+}
+
+func sanitizeValue(v interface{}, p SanitizePolicy) interface{} {
+	switch v := v.(type) {
+	case string:
+		return p.SanitizeField(v)
+	case error:
+		if s := v.Error(); !isSanitized(s) {
+			return p.SanitizeField(s)
+		}
+	case Fields:
+		return SanitizeFields(v, p)
+	case map[string]interface{}:
+		return map[string]interface{}(SanitizeFields(v, p))
+	}
+	return v
+}
+
+// SanitizeEntry returns entry with the message and fields sanitized by p,
+// def being the default of the formatter. entry itself is not modified; it
+// is returned as is when there is nothing to do.
+func SanitizeEntry(entry *Entry, p SanitizePolicy, def SanitizeMode) *Entry {
+	p = p.Resolve(def)
+	if p.Message == SanitizeNone && p.Fields == SanitizeNone {
+		return entry
+	}
+
+	sanitized := *entry
+	sanitized.Message = p.SanitizeMessage(entry.Message)
+	if p.Fields != SanitizeNone {
+		sanitized.Data = SanitizeFields(entry.Data, p)
+	}
+	return &sanitized
+}
@@ -0,0 +1,147 @@
+package logrus
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	crlfAttack = "login failed\r\ntime=\"2017-07-05T10:56:30Z\" level=info msg=\"login ok\" user=admin"
+	ansiAttack = "\x1b[2J\x1b[1;1Hrm -rf /\x1b]0;owned\x07"
+)
+
+func TestSanitize(t *testing.T) {
+	assert.Equal(t, "a\\r\\nb", Sanitize("a\r\nb", SanitizeEscape, ""))
+	assert.Equal(t, "a\\r\\nb", Sanitize("a\r\nb", SanitizeDefault, ""))
+	assert.Equal(t, "a??b", Sanitize("a\r\nb", SanitizeReplace, ""))
+	assert.Equal(t, "a__b", Sanitize("a\r\nb", SanitizeReplace, "_"))
+	assert.Equal(t, "ab", Sanitize("a\r\nb", SanitizeStrip, ""))
+	assert.Equal(t, "a\r\nb", Sanitize("a\r\nb", SanitizeNone, ""))
+
+	assert.Equal(t, `\x1b[31mred\x1b[0m`, Sanitize("\x1b[31mred\x1b[0m", SanitizeEscape, ""))
+	assert.Equal(t, `\u009b31m`, Sanitize("\u009b31m", SanitizeEscape, ""))
+	assert.Equal(t, `a\u2028b\u202e`, Sanitize("a\u2028b\u202e", SanitizeEscape, ""))
+	assert.Equal(t, `\xff`, Sanitize("\xff", SanitizeEscape, ""))
+	assert.Equal(t, "tab\tdéjà vu ✓", Sanitize("tab\tdéjà vu ✓", SanitizeEscape, ""))
+}
+
+func TestSanitizeEntry(t *testing.T) {
+	entry := WithFields(Fields{
+		"a\nb":  "x\ny",
+		"err":   errors.New("e\r\n"),
+		"size":  10,
+		"inner": Fields{"k": "\x1b[0m"},
+	})
+	entry.Message = "m\n"
+
+	sanitized := SanitizeEntry(entry, SanitizePolicy{Message: SanitizeStrip}, SanitizeEscape)
+	assert.Equal(t, "m", sanitized.Message)
+	assert.Equal(t, Fields{
+		`a\nb`:  `x\ny`,
+		"err":   `e\r\n`,
+		"size":  10,
+		"inner": Fields{"k": `\x1b[0m`},
+	}, sanitized.Data)
+
+	// the entry itself is left alone
+	assert.Equal(t, "m\n", entry.Message)
+	assert.Equal(t, "x\ny", entry.Data["a\nb"])
+
+	assert.True(t, entry == SanitizeEntry(entry, SanitizePolicy{}, SanitizeNone))
+}
+
+func TestTextFormatterSanitize(t *testing.T) {
+	withEnv(t, nil, func() {
+		entry := WithFields(Fields{"user": crlfAttack, "term": ansiAttack, "evil\nkey": 1})
+		entry.Level = InfoLevel
+		entry.Message = crlfAttack
+
+		for _, f := range []*TextFormatter{{}, {ForceColors: true}} {
+			b, err := f.Format(entry)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, strings.Count(string(b), "\n"), string(b))
+			assert.NotContains(t, string(b), "\r")
+			assert.NotContains(t, string(b), "\x1b[2J")
+			assert.NotContains(t, string(b), "\x07")
+		}
+
+		f := &TextFormatter{DisableTimestamp: true, Sanitize: SanitizePolicy{Message: SanitizeStrip, Fields: SanitizeReplace}}
+		b, err := f.Format(WithField("user", "a\r\nb"))
+		assert.NoError(t, err)
+		assert.Equal(t, "level=PANIC msg= user=\"a??b\" \n", string(b))
+
+		f = &TextFormatter{DisableTimestamp: true, Sanitize: SanitizePolicy{Message: SanitizeNone}}
+		entry = WithField("n", 1)
+		entry.Message = "\x1b[31m"
+		b, err = f.Format(entry)
+		assert.NoError(t, err)
+		assert.Equal(t, "level=PANIC msg=\"\\x1b[31m\" n=1 \n", string(b))
+	})
+}
+
+func TestConsoleFormatterSanitize(t *testing.T) {
+	f := &ConsoleFormatter{DisableColors: true, DisableCaller: true, DisableTimestamp: true}
+
+	entry := WithFields(Fields{"term": ansiAttack, "err": errors.New("x\r\nINFO  forged")})
+	entry.Level = InfoLevel
+	entry.Message = "two\r\nlines"
+
+	b, err := f.Format(entry)
+	assert.NoError(t, err)
+	assert.Equal(t, "INFO  two\\r\n    lines\n    err: x\\r\\nINFO  forged\n    term: \"\\x1b[2J\\x1b[1;1Hrm -rf /\\x1b]0;owned\\a\"\n", string(b))
+}
+
+func TestPrintFormatSanitize(t *testing.T) {
+	record := &LogRecord{Level: WarnLevel, Message: crlfAttack + ansiAttack}
+
+	message := NewPrintFormat("[%L] %M").Format(record)
+	assert.Equal(t, 1, strings.Count(message, "\n"))
+	assert.NotContains(t, message, "\r")
+	assert.NotContains(t, message, "\x1b")
+
+	pf := NewPrintFormat("%M")
+	pf.Sanitize = SanitizePolicy{Message: SanitizeNone}
+	assert.Equal(t, crlfAttack+ansiAttack+"\n", pf.Format(record))
+}
+
+func TestPrintFormatFields(t *testing.T) {
+	record := &LogRecord{Level: WarnLevel, Message: "hi", Data: Fields{"user": "a b", "n": 1, "err\n": errors.New("x\ny")}}
+
+	message := NewPrintFormat("[%L] %M %F").Format(record)
+	assert.Equal(t, `[WARN] hi err\n="x\ny" n=1 user="a b"`+"\n", message)
+}
+
+func TestLogFormatterSanitize(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &LogFormatter{PrintFormat: "[%L] %M"}
+
+	logger.Warn(crlfAttack + ansiAttack)
+
+	assert.Equal(t, "[WARN] login failed\\r\\ntime=\"2017-07-05T10:56:30Z\" level=info msg=\"login ok\" user=admin"+
+		"\\x1b[2J\\x1b[1;1Hrm -rf /\\x1b]0;owned\\x07\n", buffer.String())
+}
+
+func TestJSONFormatterSanitize(t *testing.T) {
+	entry := WithField("user", crlfAttack)
+	entry.Message = ansiAttack
+
+	b, err := (&JSONFormatter{}).Format(entry)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(b), "\n"))
+	fields := Fields{}
+	assert.NoError(t, json.Unmarshal(b, &fields))
+	assert.Equal(t, ansiAttack, fields["msg"])
+
+	b, err = (&JSONFormatter{Sanitize: SanitizePolicy{Message: SanitizeStrip, Fields: SanitizeStrip}}).Format(entry)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(b, &fields))
+	assert.Equal(t, "[2J[1;1Hrm -rf /]0;owned", fields["msg"])
+	assert.NotContains(t, fields["user"], "\r")
+}
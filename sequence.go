@@ -0,0 +1,327 @@
+package logrus
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// StartID identifies the current run of the process, so that the sequence
+// numbers of entries logged before and after a restart can be told apart.
+var StartID = newStartID()
+
+func newStartID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Sequencer numbers the entries of a logger, so that lost, duplicated or
+// reordered lines can be detected downstream, e.g. behind asynchronous hooks
+// or log shippers, see SequenceChecker:
+//
+//	log.Sequencer = &logrus.Sequencer{}
+//
+// Every entry gets the next number, starting at 1, in SeqField and the
+// StartID of the process in StartIDField, so that all formatters and hooks
+// see them. Numbers are given when the entry is logged: entries logged
+// concurrently may reach Out in another order than their numbers.
+type Sequencer struct {
+	// SeqField holds the sequence number, defaults to "seq".
+	SeqField string
+
+	// StartIDField holds the start id, defaults to "start_id".
+	StartIDField string
+
+	// StartID defaults to the StartID of the process.
+	StartID string
+
+	last uint64
+}
+
+// Next returns the next sequence number.
+func (s *Sequencer) Next() uint64 {
+	return atomic.AddUint64(&s.last, 1)
+}
+
+// SequenceEntry returns a copy of entry with the next sequence number and the
+// start id added to its fields.
+func (s *Sequencer) SequenceEntry(entry *Entry) *Entry {
+	sequenced := *entry
+	sequenced.Data = make(Fields, len(entry.Data)+2)
+	for k, v := range entry.Data {
+		sequenced.Data[k] = v
+	}
+	sequenced.Data[s.seqField()] = s.Next()
+	sequenced.Data[s.startIDField()] = s.startID()
+	return &sequenced
+}
+
+func (s *Sequencer) seqField() string {
+	if s.SeqField == "" {
+		return "seq"
+	}
+	return s.SeqField
+}
+
+func (s *Sequencer) startIDField() string {
+	if s.StartIDField == "" {
+		return "start_id"
+	}
+	return s.StartIDField
+}
+
+func (s *Sequencer) startID() string {
+	if s.StartID == "" {
+		return StartID
+	}
+	return s.StartID
+}
+
+// SequenceIssueKind is the kind of a SequenceIssue.
+type SequenceIssueKind int
+
+const (
+	// SequenceGap is a range of numbers never seen.
+	SequenceGap SequenceIssueKind = iota
+	// SequenceDuplicate is a number seen more than once.
+	SequenceDuplicate
+	// SequenceReorder is a number seen after a greater one.
+	SequenceReorder
+)
+
+func (k SequenceIssueKind) String() string {
+	switch k {
+	case SequenceGap:
+		return "gap"
+	case SequenceDuplicate:
+		return "duplicate"
+	case SequenceReorder:
+		return "reorder"
+	}
+	return "unknown"
+}
+
+// SequenceIssue is a gap, duplicate or reordering found by a SequenceChecker.
+type SequenceIssue struct {
+	Kind    SequenceIssueKind
+	StartID string
+	// From and To are the numbers concerned, equal but for gaps.
+	From, To uint64
+	// Line is the line the duplicate or reordered number was found on, 0 for
+	// gaps.
+	Line int
+}
+
+func (i SequenceIssue) String() string {
+	s := fmt.Sprintf("%s: start_id %s seq %d", i.Kind, i.StartID, i.From)
+	if i.To != i.From {
+		s += fmt.Sprintf("-%d", i.To)
+	}
+	if i.Line > 0 {
+		s += fmt.Sprintf(" (line %d)", i.Line)
+	}
+	return s
+}
+
+// SequenceReport is the result of a SequenceChecker.
+type SequenceReport struct {
+	// Lines is the number of lines read, Entries the number of them which
+	// had a sequence number.
+	Lines, Entries int
+	Issues         []SequenceIssue
+}
+
+// OK reports whether no issue was found.
+func (r *SequenceReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// SequenceChecker reads the lines logged with a Sequencer, as written by
+// JSONFormatter, TextFormatter, colored or not, or a PrintFormat with %F such
+// as the one of the file hook, and reports gaps, duplicates and reorderings,
+// separately for every start id. The first number seen for a start id is
+// taken as its start, so that a rotated file can be checked on its own;
+// numbers found later below it are reported as reordered.
+//
+//	report, err := (&logrus.SequenceChecker{}).Check(file)
+type SequenceChecker struct {
+	// SeqField and StartIDField are the fields of the Sequencer, default to
+	// "seq" and "start_id".
+	SeqField, StartIDField string
+
+	// Parse, when set, extracts the start id and sequence number of a line
+	// in another format. ok is false for lines without sequence number.
+	Parse func(line []byte) (startID string, seq uint64, ok bool)
+}
+
+// Check reads r until EOF.
+func (c *SequenceChecker) Check(r io.Reader) (*SequenceReport, error) {
+	parse := c.Parse
+	if parse == nil {
+		parse = c.parse()
+	}
+
+	report := &SequenceReport{}
+	streams := map[string]*sequenceStream{}
+	var order []string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for scanner.Scan() {
+		report.Lines++
+		startID, seq, ok := parse(scanner.Bytes())
+		if !ok {
+			continue
+		}
+		report.Entries++
+
+		stream := streams[startID]
+		if stream == nil {
+			stream = &sequenceStream{first: seq, last: seq}
+			streams[startID] = stream
+			order = append(order, startID)
+			continue
+		}
+		if kind, ok := stream.add(seq); !ok {
+			report.Issues = append(report.Issues, SequenceIssue{
+				Kind: kind, StartID: startID, From: seq, To: seq, Line: report.Lines,
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return report, err
+	}
+
+	for _, startID := range order {
+		for _, gap := range streams[startID].missing {
+			report.Issues = append(report.Issues, SequenceIssue{
+				Kind: SequenceGap, StartID: startID, From: gap.from, To: gap.to,
+			})
+		}
+	}
+	return report, nil
+}
+
+func (c *SequenceChecker) parse() func([]byte) (string, uint64, bool) {
+	seqField, startIDField := c.SeqField, c.StartIDField
+	if seqField == "" {
+		seqField = "seq"
+	}
+	if startIDField == "" {
+		startIDField = "start_id"
+	}
+	seqRegexp := textFieldRegexp(seqField)
+	startIDRegexp := textFieldRegexp(startIDField)
+
+	return func(line []byte) (string, uint64, bool) {
+		if len(line) > 0 && line[0] == '{' {
+			var fields map[string]interface{}
+			decoder := json.NewDecoder(bytes.NewReader(line))
+			decoder.UseNumber()
+			if err := decoder.Decode(&fields); err != nil {
+				return "", 0, false
+			}
+			n, ok := fields[seqField].(json.Number)
+			if !ok {
+				return "", 0, false
+			}
+			seq, err := strconv.ParseUint(string(n), 10, 64)
+			if err != nil {
+				return "", 0, false
+			}
+			startID, _ := fields[startIDField].(string)
+			return startID, seq, true
+		}
+
+		if bytes.IndexByte(line, '\x1b') >= 0 {
+			// colored by TextFormatter
+			line = ansiRegexp.ReplaceAll(line, nil)
+		}
+		m := seqRegexp.FindSubmatch(line)
+		if m == nil {
+			return "", 0, false
+		}
+		seq, err := strconv.ParseUint(string(m[2]), 10, 64)
+		if err != nil {
+			return "", 0, false
+		}
+		var startID string
+		if m := startIDRegexp.FindSubmatch(line); m != nil {
+			startID = string(m[2])
+		}
+		return startID, seq, true
+	}
+}
+
+// ansiRegexp matches the SGR escape sequences of the colors.
+var ansiRegexp = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// textFieldRegexp matches key=value or key="value" as written by
+// TextFormatter, or by PrintFormat with %F.
+func textFieldRegexp(key string) *regexp.Regexp {
+	return regexp.MustCompile(`(?:^|\s)` + regexp.QuoteMeta(key) + `=("?)([^\s"]+)`)
+}
+
+type sequenceRange struct {
+	from, to uint64
+}
+
+// sequenceStream tracks the numbers seen for a start id: all the numbers
+// between first and last have been seen, but the missing ones.
+type sequenceStream struct {
+	first, last uint64
+	missing     []sequenceRange // sorted
+}
+
+// add records seq, returning the kind of issue and false when it isn't the
+// next number.
+func (s *sequenceStream) add(seq uint64) (SequenceIssueKind, bool) {
+	switch {
+	case seq == s.last+1:
+		s.last = seq
+		return 0, true
+	case seq > s.last:
+		s.missing = append(s.missing, sequenceRange{s.last + 1, seq - 1})
+		s.last = seq
+		return 0, true
+	case seq < s.first:
+		if seq+1 < s.first {
+			s.missing = append([]sequenceRange{{seq + 1, s.first - 1}}, s.missing...)
+		}
+		s.first = seq
+		return SequenceReorder, false
+	}
+
+	i := sort.Search(len(s.missing), func(i int) bool { return s.missing[i].to >= seq })
+	if i == len(s.missing) || s.missing[i].from > seq {
+		return SequenceDuplicate, false
+	}
+
+	// fill the hole, splitting the range it was in
+	r := s.missing[i]
+	switch {
+	case r.from == r.to:
+		s.missing = append(s.missing[:i], s.missing[i+1:]...)
+	case seq == r.from:
+		s.missing[i].from++
+	case seq == r.to:
+		s.missing[i].to--
+	default:
+		s.missing = append(s.missing[:i+1], s.missing[i:]...)
+		s.missing[i].to = seq - 1
+		s.missing[i+1].from = seq + 1
+	}
+	return SequenceReorder, false
+}
@@ -0,0 +1,131 @@
+package logrus
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSequencer(t *testing.T) {
+	var buffer bytes.Buffer
+	hook := &redactRecorder{}
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &JSONFormatter{}
+	logger.Hooks.Add(hook)
+	logger.Sequencer = &Sequencer{StartID: "run1"}
+
+	entry := logger.WithField("a", 1)
+	entry.Info("one")
+	entry.Info("two")
+	assert.Equal(t, Fields{"a": 1}, entry.Data)
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Len(t, lines, 2)
+	for i, line := range lines {
+		fields := Fields{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &fields))
+		assert.Equal(t, float64(i+1), fields["seq"])
+		assert.Equal(t, "run1", fields["start_id"])
+		assert.Equal(t, uint64(i+1), hook.entries[i].Data["seq"])
+	}
+
+	report, err := (&SequenceChecker{}).Check(&buffer)
+	assert.NoError(t, err)
+	assert.True(t, report.OK())
+	assert.Equal(t, 2, report.Entries)
+}
+
+func TestSequencerConcurrent(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &TextFormatter{DisableColors: true}
+	logger.Sequencer = &Sequencer{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				logger.Info("hello")
+			}
+		}()
+	}
+	wg.Wait()
+
+	report, err := (&SequenceChecker{}).Check(&buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, 800, report.Entries)
+	for _, issue := range report.Issues {
+		// reorderings are expected, never gaps nor duplicates
+		assert.Equal(t, SequenceReorder, issue.Kind, issue.String())
+		assert.Equal(t, StartID, issue.StartID)
+	}
+}
+
+func TestSequenceChecker(t *testing.T) {
+	input := strings.Join([]string{
+		`{"msg":"a","seq":1,"start_id":"r1"}`,
+		`{"msg":"b","seq":2,"start_id":"r1"}`,
+		`{"msg":"c","seq":5,"start_id":"r1"}`,
+		`{"msg":"d","seq":4,"start_id":"r1"}`,
+		`{"msg":"e","seq":5,"start_id":"r1"}`,
+		`not a log line`,
+		`time="2017-07-05T10:56:30Z" level=info msg=f seq=1 start_id=r2`,
+		`time="2017-07-05T10:56:30Z" level=info msg=g seq=9 start_id=r2`,
+		`{"msg":"h","seq":10,"start_id":"r1"}`,
+	}, "\n")
+
+	report, err := (&SequenceChecker{}).Check(strings.NewReader(input))
+	assert.NoError(t, err)
+	assert.Equal(t, 9, report.Lines)
+	assert.Equal(t, 8, report.Entries)
+	assert.Equal(t, []SequenceIssue{
+		{Kind: SequenceReorder, StartID: "r1", From: 4, To: 4, Line: 4},
+		{Kind: SequenceDuplicate, StartID: "r1", From: 5, To: 5, Line: 5},
+		{Kind: SequenceGap, StartID: "r1", From: 3, To: 3},
+		{Kind: SequenceGap, StartID: "r1", From: 6, To: 9},
+		{Kind: SequenceGap, StartID: "r2", From: 2, To: 8},
+	}, report.Issues)
+	assert.Equal(t, "gap: start_id r1 seq 6-9", report.Issues[3].String())
+	assert.Equal(t, "duplicate: start_id r1 seq 5 (line 5)", report.Issues[1].String())
+}
+
+func TestSequenceCheckerColors(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &TextFormatter{ForceColors: true}
+	logger.Sequencer = &Sequencer{StartID: "run1"}
+
+	for i := 0; i < 5; i++ {
+		logger.Info("hi")
+	}
+	assert.Contains(t, buffer.String(), "seq\x1b[0m=")
+
+	report, err := (&SequenceChecker{}).Check(&buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, report.Entries)
+	assert.True(t, report.OK(), "%v", report.Issues)
+}
+
+func TestSequenceStreamFillsGaps(t *testing.T) {
+	s := &sequenceStream{first: 10, last: 10}
+	_, ok := s.add(20)
+	assert.True(t, ok)
+
+	for _, seq := range []uint64{15, 11, 19, 7} {
+		kind, ok := s.add(seq)
+		assert.False(t, ok)
+		assert.Equal(t, SequenceReorder, kind)
+	}
+	kind, _ := s.add(15)
+	assert.Equal(t, SequenceDuplicate, kind)
+	assert.Equal(t, []sequenceRange{{8, 9}, {12, 14}, {16, 18}}, s.missing)
+}
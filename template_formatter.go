@@ -0,0 +1,311 @@
+package logrus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+// DefaultTemplate is the template of a TemplateFormatter created without
+// NewTemplateFormatter.
+const DefaultTemplate = `{{timefmt "2006-01-02T15:04:05Z07:00" .Time}} {{color .Level (pad 5 .Level)}} {{.Message}}` +
+	`{{if .Data}} {{logfmt .Data}}{{end}}`
+
+// TemplateEntry is the data a TemplateFormatter executes its template with.
+type TemplateEntry struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	Data    Fields
+	// Caller is "file.go:line" of the logging call, only filled when the
+	// template refers to it.
+	Caller string
+	// Logger is the name of the logger, see Logger.Name.
+	Logger string
+}
+
+// TemplateFormatter formats entries with a `text/template`, for layouts the
+// other formatters can't express:
+//
+//	f, err := logrus.NewTemplateFormatter(
+//		`{{timefmt "15:04:05" .Time}} {{color .Level (pad 5 .Level)}} {{.Message}}` +
+//			`{{if .Data}} {{logfmt .Data}}{{end}}`, nil)
+//
+// Besides the standard template functions, the following are available:
+//
+//	color   {{color .Level "text"}}, {{color "red" "text"}} or {{color "#ff8700" "text"}}
+//	pad     {{pad 10 .Logger}}, negative widths align to the right
+//	json    {{json .Data}}
+//	logfmt  {{logfmt .Data}}, key=value pairs sorted by key
+//	timefmt {{timefmt "2006-01-02" .Time}}
+//	default {{default "-" (index .Data "user")}}
+//
+// A newline is appended to the output unless it already ends with one. The
+// zero value formats with DefaultTemplate.
+type TemplateFormatter struct {
+	// Set to true to bypass checking for a TTY before outputting colors.
+	ForceColors bool
+
+	// Force disabling colors.
+	DisableColors bool
+
+	// Theme of the level colors, defaults to DefaultColorTheme.
+	Theme *ColorTheme
+
+	// Location .Time is converted to, see Logger.Clock for the time itself.
+	Location *time.Location
+
+	// Sanitize .Message and .Data, escaped by default, see SanitizePolicy.
+	Sanitize SanitizePolicy
+
+	// The template bound to a color function for each ColorMode, so that the
+	// mode can be chosen per entry without touching shared state.
+	tmpls       [ColorsTrue + 1]*template.Template
+	needsCaller bool
+	once        sync.Once
+}
+
+// NewTemplateFormatter parses text once, so that errors in the template,
+// colors given by name included, are reported here rather than on every log
+// line. funcs may add or override template functions and can be nil.
+func NewTemplateFormatter(text string, funcs template.FuncMap) (*TemplateFormatter, error) {
+	f := &TemplateFormatter{}
+	if err := f.parse(text, funcs); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *TemplateFormatter) parse(text string, funcs template.FuncMap) error {
+	f.needsCaller = strings.Contains(text, ".Caller")
+
+	tmpl, err := template.New("entry").Funcs(template.FuncMap{
+		"color":   f.colorFunc(ColorsOff),
+		"pad":     templatePad,
+		"json":    templateJSON,
+		"logfmt":  templateLogfmt,
+		"timefmt": templateTimefmt,
+		"default": templateDefault,
+	}).Funcs(funcs).Parse(text)
+	if err != nil {
+		return fmt.Errorf("logrus: invalid template: %v", err)
+	}
+	if _, ok := funcs["color"]; !ok {
+		for _, t := range tmpl.Templates() {
+			if t.Tree == nil {
+				continue
+			}
+			if err := checkColors(t.Tree.Root); err != nil {
+				return fmt.Errorf("logrus: invalid template: %v", err)
+			}
+		}
+	}
+	f.tmpls[ColorsOff] = tmpl
+	for mode := Colors16; mode <= ColorsTrue; mode++ {
+		clone, err := tmpl.Clone()
+		if err != nil {
+			return fmt.Errorf("logrus: invalid template: %v", err)
+		}
+		if _, ok := funcs["color"]; !ok {
+			clone.Funcs(template.FuncMap{"color": f.colorFunc(mode)})
+		}
+		f.tmpls[mode] = clone
+	}
+	return nil
+}
+
+// checkColors parses the colors given as string constants to the color
+// function in the template tree node.
+func checkColors(node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, node := range n.Nodes {
+			if err := checkColors(node); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		return checkColors(n.Pipe)
+	case *parse.IfNode:
+		return checkBranchColors(&n.BranchNode)
+	case *parse.RangeNode:
+		return checkBranchColors(&n.BranchNode)
+	case *parse.WithNode:
+		return checkBranchColors(&n.BranchNode)
+	case *parse.TemplateNode:
+		return checkColors(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		for _, cmd := range n.Cmds {
+			if err := checkColors(cmd); err != nil {
+				return err
+			}
+		}
+	case *parse.CommandNode:
+		if len(n.Args) > 1 {
+			if ident, ok := n.Args[0].(*parse.IdentifierNode); ok && ident.Ident == "color" {
+				if c, ok := n.Args[1].(*parse.StringNode); ok {
+					if _, err := ParseColor(c.Text); err != nil {
+						return fmt.Errorf("invalid color %q", c.Text)
+					}
+				}
+			}
+		}
+		for _, arg := range n.Args {
+			if err := checkColors(arg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkBranchColors(n *parse.BranchNode) error {
+	for _, node := range []parse.Node{n.Pipe, n.List, n.ElseList} {
+		if err := checkColors(node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *TemplateFormatter) Format(entry *Entry) ([]byte, error) {
+	f.once.Do(func() {
+		if f.tmpls[ColorsOff] == nil {
+			if err := f.parse(DefaultTemplate, nil); err != nil {
+				panic(err)
+			}
+		}
+	})
+	entry = SanitizeEntry(entry, f.Sanitize, SanitizeEscape)
+
+	data := &TemplateEntry{
+		Time:    timeIn(entry.Time, f.Location),
+		Level:   entry.Level,
+		Message: entry.Message,
+		Data:    entry.Data,
+	}
+	if entry.Logger != nil {
+		data.Logger = entry.Logger.Name
+	}
+	if f.needsCaller {
+		if frame, ok := getCallerFrame(); ok {
+			data.Caller = filepath.Base(frame.File) + ":" + strconv.Itoa(frame.Line)
+		}
+	}
+
+	mode := entryColorMode(entry, f.ForceColors, f.DisableColors)
+
+	b := &bytes.Buffer{}
+	if err := f.tmpls[mode].Execute(b, data); err != nil {
+		return nil, fmt.Errorf("Failed to execute template, %v", err)
+	}
+	if b.Len() == 0 || b.Bytes()[b.Len()-1] != '\n' {
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+// colorFunc returns the color template function for an output of the given
+// mode, which colors s by level or by color as accepted by ParseColor.
+func (f *TemplateFormatter) colorFunc(mode ColorMode) func(c interface{}, s interface{}) (string, error) {
+	return func(c interface{}, s interface{}) (string, error) {
+		var color Color
+		switch c := c.(type) {
+		case Level:
+			color = themeOrDefault(f.Theme).Level(c)
+		case string:
+			var err error
+			if color, err = ParseColor(c); err != nil {
+				return "", err
+			}
+		default:
+			return "", fmt.Errorf("color takes a Level or a color, not %T", c)
+		}
+		return color.Sprint(mode, fmt.Sprint(s)), nil
+	}
+}
+
+func templatePad(width int, v interface{}) string {
+	return fmt.Sprintf("%*v", -width, v)
+}
+
+func templateJSON(v interface{}) (string, error) {
+	if err, ok := v.(error); ok {
+		v = err.Error()
+	}
+	if fields, ok := v.(Fields); ok {
+		data := make(Fields, len(fields))
+		for k, v := range fields {
+			if err, ok := v.(error); ok {
+				v = err.Error()
+			}
+			data[k] = v
+		}
+		v = data
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func templateLogfmt(fields Fields) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	b := &bytes.Buffer{}
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		value := fields[k]
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		if s, ok := value.(string); ok && !needsQuoting(s) {
+			fmt.Fprintf(b, "%s=%q", k, s)
+		} else {
+			fmt.Fprintf(b, "%s=%v", k, value)
+		}
+	}
+	return b.String()
+}
+
+func templateTimefmt(layout string, t time.Time) string {
+	return t.Format(layout)
+}
+
+// templateDefault returns def when v is missing, nil or a zero value.
+func templateDefault(def interface{}, v interface{}) interface{} {
+	if v == nil {
+		return def
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice, reflect.String, reflect.Array:
+		if rv.Len() == 0 {
+			return def
+		}
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return def
+		}
+	}
+	return v
+}
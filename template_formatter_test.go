@@ -0,0 +1,87 @@
+package logrus
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateFormatter(t *testing.T) {
+	f, err := NewTemplateFormatter(
+		`{{timefmt "15:04:05" .Time}} [{{pad 5 .Level}}] {{pad -6 .Logger}} {{.Message}}`+
+			`{{if eq .Level.String "ERROR"}} !{{end}} user={{default "-" (index .Data "user")}} {{logfmt .Data}}`, nil)
+	assert.NoError(t, err)
+
+	logger := New()
+	logger.Name = "api"
+
+	entry := NewEntry(logger).WithFields(Fields{"error": errors.New("x y"), "size": 10})
+	entry.Time = time.Date(2017, 7, 5, 10, 56, 30, 0, time.UTC)
+	entry.Level = ErrorLevel
+	entry.Message = "member not login"
+
+	b, err := f.Format(entry)
+	assert.NoError(t, err)
+	assert.Equal(t, `10:56:30 [ERROR]    api member not login ! user=- error="x y" size=10`+"\n", string(b))
+}
+
+func TestTemplateFormatterHelpers(t *testing.T) {
+	f, err := NewTemplateFormatter(`{{color .Level .Message}} {{color "green" "ok"}} {{json .Data}} {{shout .Message}}{{.Caller}}`+"\n",
+		template.FuncMap{"shout": strings.ToUpper})
+	assert.NoError(t, err)
+	f.ForceColors = true
+
+	entry := WithField("err", errors.New("boom"))
+	entry.Level = WarnLevel
+	entry.Message = "hi"
+
+	_, _, line, _ := runtime.Caller(0)
+	b, err := f.Format(entry)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("\x1b[33mhi\x1b[0m \x1b[32mok\x1b[0m {\"err\":\"boom\"} HItemplate_formatter_test.go:%d\n", line+1), string(b))
+
+	f.DisableColors = true
+	b, err = f.Format(entry)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(b), "hi ok "))
+}
+
+func TestTemplateFormatterErrors(t *testing.T) {
+	_, err := NewTemplateFormatter(`{{.Message`, nil)
+	assert.Error(t, err)
+
+	_, err = NewTemplateFormatter(`{{nosuchfunc .Message}}`, nil)
+	assert.Error(t, err)
+
+	// colors given by name are checked once
+	_, err = NewTemplateFormatter(`{{if .Data}}{{color "purple" .Message}}{{end}}`, nil)
+	assert.EqualError(t, err, `logrus: invalid template: invalid color "purple"`)
+	_, err = NewTemplateFormatter(`{{color "purple" .Message}}`, template.FuncMap{"color": fmt.Sprint})
+	assert.NoError(t, err)
+
+	// or on each entry when they come from it
+	f, err := NewTemplateFormatter(`{{color (index .Data "color") .Message}}`, nil)
+	assert.NoError(t, err)
+	f.ForceColors = true
+	_, err = f.Format(WithField("color", "purple"))
+	assert.Error(t, err)
+}
+
+func TestTemplateFormatterZeroValue(t *testing.T) {
+	f := &TemplateFormatter{DisableColors: true}
+
+	entry := WithField("size", 10)
+	entry.Time = time.Date(2017, 7, 5, 10, 56, 30, 0, time.UTC)
+	entry.Level = InfoLevel
+	entry.Message = "hi"
+
+	b, err := f.Format(entry)
+	assert.NoError(t, err)
+	assert.Equal(t, "2017-07-05T10:56:30Z INFO  hi size=10\n", string(b))
+}
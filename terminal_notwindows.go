@@ -12,10 +12,14 @@ import (
 	"unsafe"
 )
 
-// IsTerminal returns true if the given file descriptor is a terminal.
+// IsTerminal returns true if stdout is a terminal.
 func IsTerminal() bool {
-	fd := syscall.Stdout
+	return isTerminalFd(uintptr(syscall.Stdout))
+}
+
+// isTerminalFd returns true if the given file descriptor is a terminal.
+func isTerminalFd(fd uintptr) bool {
 	var termios Termios
-	_, _, err := syscall.Syscall6(syscall.SYS_IOCTL, uintptr(fd), ioctlReadTermios, uintptr(unsafe.Pointer(&termios)), 0, 0, 0)
+	_, _, err := syscall.Syscall6(syscall.SYS_IOCTL, fd, ioctlReadTermios, uintptr(unsafe.Pointer(&termios)), 0, 0, 0)
 	return err == 0
 }
@@ -18,10 +18,14 @@ var (
 	procGetConsoleMode = kernel32.NewProc("GetConsoleMode")
 )
 
-// IsTerminal returns true if the given file descriptor is a terminal.
+// IsTerminal returns true if stdout is a terminal.
 func IsTerminal() bool {
-	fd := syscall.Stdout
+	return isTerminalFd(uintptr(syscall.Stdout))
+}
+
+// isTerminalFd returns true if the given file descriptor is a terminal.
+func isTerminalFd(fd uintptr) bool {
 	var st uint32
-	r, _, e := syscall.Syscall(procGetConsoleMode.Addr(), 2, uintptr(fd), uintptr(unsafe.Pointer(&st)), 0)
+	r, _, e := syscall.Syscall(procGetConsoleMode.Addr(), 2, fd, uintptr(unsafe.Pointer(&st)), 0)
 	return r != 0 && e == 0
 }
@@ -3,39 +3,22 @@ package logrus
 import (
 	"bytes"
 	"fmt"
-	"runtime"
 	"sort"
 	"strings"
 	"time"
 )
 
-//Text color codes:
-//30=black 31=red 32=green 33=yellow 34=blue 35=magenta 36=cyan 37=white
-//Background color codes:
-//40=black 41=red 42=green 43=yellow 44=blue 45=magenta 46=cyan 47=white
-
-const (
-	nocolor = 0
-	black   = 30
-	red     = 31
-	green   = 36
-	yellow  = 33
-	blue    = 34
-	gray    = 37
-)
-
 var (
 	baseTimestamp time.Time
-	isTerminal    bool
 )
 
 func init() {
 	baseTimestamp = time.Now()
-	isTerminal = IsTerminal()
 }
 
-func miniTS() int {
-	return int(time.Since(baseTimestamp) / time.Second)
+// miniTS is the number of seconds from the start of the program to t.
+func miniTS(t time.Time) int {
+	return int(t.Sub(baseTimestamp) / time.Second)
 }
 
 type TextFormatter struct {
@@ -45,6 +28,9 @@ type TextFormatter struct {
 	// Force disabling colors.
 	DisableColors bool
 
+	// Theme of the colors, defaults to DefaultColorTheme.
+	Theme *ColorTheme
+
 	// Disable timestamp logging. useful when output is redirected to logging
 	// system that already adds timestamps.
 	DisableTimestamp bool
@@ -56,10 +42,16 @@ type TextFormatter struct {
 	// TimestampFormat to use for display when a full timestamp is printed
 	TimestampFormat string
 
+	// Location of the full timestamps, the entry's by default.
+	Location *time.Location
+
 	// The fields are sorted by default for a consistent output. For applications
 	// that log extremely frequently and don't use the JSON formatter this may not
 	// be desired.
 	DisableSorting bool
+
+	// Sanitize escapes the control characters by default, see SanitizePolicy.
+	Sanitize SanitizePolicy
 }
 
 func (f *TextFormatter) Format(entry *Entry) ([]byte, error) {
@@ -76,22 +68,22 @@ func (f *TextFormatter) Format(entry *Entry) ([]byte, error) {
 
 	PrefixFieldClashes(entry.Data)
 
-	isColorTerminal := isTerminal && (runtime.GOOS != "windows")
-	isColored := (f.ForceColors || isColorTerminal) && !f.DisableColors
+	colorMode := entryColorMode(entry, f.ForceColors, f.DisableColors)
+	sanitize := f.Sanitize.Resolve(SanitizeEscape)
 
 	if f.TimestampFormat == "" {
 		f.TimestampFormat = DefaultTimestampFormat
 	}
-	if isColored {
-		f.printColored(b, entry, keys)
+	if colorMode != ColorsOff {
+		f.printColored(b, entry, keys, colorMode, sanitize)
 	} else {
 		if !f.DisableTimestamp {
-			f.appendKeyValue(b, "time", entry.Time.Format(f.TimestampFormat))
+			f.appendKeyValue(b, "time", timeIn(entry.Time, f.Location).Format(f.TimestampFormat), SanitizeNone)
 		}
-		f.appendKeyValue(b, "level", entry.Level.String())
-		f.appendKeyValue(b, "msg", entry.Message)
+		f.appendKeyValue(b, "level", entry.Level.String(), SanitizeNone)
+		f.appendKeyValue(b, "msg", entry.Message, sanitize.Message)
 		for _, key := range keys {
-			f.appendKeyValue(b, key, entry.Data[key])
+			f.appendKeyValue(b, sanitize.SanitizeField(key), entry.Data[key], sanitize.Fields)
 		}
 	}
 
@@ -99,29 +91,20 @@ func (f *TextFormatter) Format(entry *Entry) ([]byte, error) {
 	return b.Bytes(), nil
 }
 
-func (f *TextFormatter) printColored(b *bytes.Buffer, entry *Entry, keys []string) {
-	var levelColor int
-	switch entry.Level {
-	case DebugLevel:
-		levelColor = gray
-	case WarnLevel:
-		levelColor = yellow
-	case ErrorLevel, FatalLevel, PanicLevel:
-		levelColor = red
-	default:
-		levelColor = blue
-	}
+func (f *TextFormatter) printColored(b *bytes.Buffer, entry *Entry, keys []string, colorMode ColorMode, sanitize SanitizePolicy) {
+	theme := themeOrDefault(f.Theme)
+	message := sanitize.SanitizeMessage(entry.Message)
 
-	levelText := strings.ToUpper(entry.Level.String())[0:4]
+	levelText := theme.Level(entry.Level).Sprint(colorMode, strings.ToUpper(entry.Level.String())[0:4])
 
 	if !f.FullTimestamp {
-		fmt.Fprintf(b, "\x1b[%dm%s\x1b[0m[%04d] %-44s ", levelColor, levelText, miniTS(), entry.Message)
+		fmt.Fprintf(b, "%s[%04d] %-44s ", levelText, miniTS(entry.Time), message)
 	} else {
-		fmt.Fprintf(b, "\x1b[%dm%s\x1b[0m[%s] %-44s ", levelColor, levelText, entry.Time.Format(f.TimestampFormat), entry.Message)
+		fmt.Fprintf(b, "%s[%s] %-44s ", levelText, timeIn(entry.Time, f.Location).Format(f.TimestampFormat), message)
 	}
 	for _, k := range keys {
-		v := entry.Data[k]
-		fmt.Fprintf(b, " \x1b[%dm%s\x1b[0m=%v", levelColor, k, v)
+		v := sanitize.SanitizeField(fmt.Sprint(entry.Data[k]))
+		fmt.Fprintf(b, " %s=%s", theme.Key(k, entry.Level).Sprint(colorMode, sanitize.SanitizeField(k)), v)
 	}
 }
 
@@ -137,21 +120,26 @@ func needsQuoting(text string) bool {
 	return true
 }
 
-func (f *TextFormatter) appendKeyValue(b *bytes.Buffer, key, value interface{}) {
-	switch value.(type) {
+// appendKeyValue writes key=value, sanitizing value with mode. Strings and
+// errors are quoted with escapes as needed, so they only need sanitizing when
+// control characters are to be replaced or stripped.
+func (f *TextFormatter) appendKeyValue(b *bytes.Buffer, key string, value interface{}, mode SanitizeMode) {
+	var text string
+	switch value := value.(type) {
 	case string:
-		if needsQuoting(value.(string)) {
-			fmt.Fprintf(b, "%v=%s ", key, value)
-		} else {
-			fmt.Fprintf(b, "%v=%q ", key, value)
-		}
+		text = value
 	case error:
-		if needsQuoting(value.(error).Error()) {
-			fmt.Fprintf(b, "%v=%s ", key, value)
-		} else {
-			fmt.Fprintf(b, "%v=%q ", key, value)
-		}
+		text = value.Error()
 	default:
-		fmt.Fprintf(b, "%v=%v ", key, value)
+		fmt.Fprintf(b, "%v=%s ", key, Sanitize(fmt.Sprint(value), mode, f.Sanitize.Replacement))
+		return
+	}
+	if mode != SanitizeEscape {
+		text = Sanitize(text, mode, f.Sanitize.Replacement)
+	}
+	if needsQuoting(text) {
+		fmt.Fprintf(b, "%v=%s ", key, text)
+	} else {
+		fmt.Fprintf(b, "%v=%q ", key, text)
 	}
 }